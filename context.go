@@ -2,12 +2,19 @@ package yevna
 
 import (
 	"context"
+	"maps"
 	"path/filepath"
+	"sync"
 )
 
 type Context struct {
 	workdir string
 	silent  bool
+	fsys    FS
+
+	// mu guards values, as they may be set by the goroutines of streaming handlers, e.g. Hash.
+	mu     sync.RWMutex
+	values map[string]any
 
 	ctx context.Context
 
@@ -34,6 +41,29 @@ func (c *Context) Workdir(wd ...string) string {
 	return c.workdir
 }
 
+// FS gets or sets the file system used by the file handlers.
+// The default is OSFS.
+func (c *Context) FS(fsys ...FS) FS {
+	if len(fsys) > 1 {
+		panic("too many arguments")
+	}
+	if len(fsys) == 1 {
+		c.fsys = fsys[0]
+	}
+	if c.fsys == nil {
+		return OSFS()
+	}
+	return c.fsys
+}
+
+// path resolves the relative path against the working directory.
+func (c *Context) path(name string) string {
+	if filepath.IsLocal(name) {
+		return filepath.Join(c.workdir, name)
+	}
+	return name
+}
+
 func (c *Context) Silent(s ...bool) bool {
 	if len(s) > 1 {
 		panic("too many arguments")
@@ -44,6 +74,26 @@ func (c *Context) Silent(s ...bool) bool {
 	return c.silent
 }
 
+// Value gets or sets the value associated with key.
+// If v is given, it sets the value and returns it.
+func (c *Context) Value(key string, v ...any) any {
+	if len(v) > 1 {
+		panic("too many arguments")
+	}
+	if len(v) == 1 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.values == nil {
+			c.values = make(map[string]any)
+		}
+		c.values[key] = v[0]
+		return v[0]
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.values[key]
+}
+
 func (c *Context) Next(in any) (any, error) {
 	c.index++
 	for c.index < len(c.handlers) {
@@ -58,15 +108,27 @@ func (c *Context) Next(in any) (any, error) {
 }
 
 func (c *Context) copy() *Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	cc := &Context{
 		silent:   c.silent,
 		workdir:  c.workdir,
+		fsys:     c.fsys,
+		values:   maps.Clone(c.values),
 		index:    -1,
 		handlers: c.handlers.Copy(),
 	}
 	return cc
 }
 
+// fork returns a copy of the Context which runs the handlers.
+func (c *Context) fork(handlers HandlersChain) *Context {
+	cc := c.copy()
+	cc.ctx = c.ctx
+	cc.handlers = handlers
+	return cc
+}
+
 func (c *Context) Use(handles ...Handler) *Context {
 	c.handlers = append(c.handlers, handles...)
 	return c
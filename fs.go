@@ -0,0 +1,179 @@
+package yevna
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// File is a file opened by FS.
+type File interface {
+	fs.File
+	io.Writer
+}
+
+// FS is a writable file system used by the file handlers.
+// The names are slash or OS separated paths,
+// relative names are resolved against the working directory by the handlers before calling FS.
+//
+// FS implements fs.StatFS and fs.ReadDirFS, so it can be used with fs.WalkDir, fs.ReadFile, etc.
+type FS interface {
+	Open(name string) (fs.File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	MkdirAll(name string, perm fs.FileMode) error
+	Rename(oldname, newname string) error
+	Remove(name string) error
+	Chmod(name string, mode fs.FileMode) error
+	Chown(name string, uid, gid int) error
+}
+
+// SymlinkFS is implemented by the FS which supports symbolic links.
+type SymlinkFS interface {
+	FS
+	Lstat(name string) (fs.FileInfo, error)
+	Readlink(name string) (string, error)
+	Symlink(oldname, newname string) error
+}
+
+// OSFS returns the FS backed by the os package, it is the default FS of Context.
+// It implements SymlinkFS.
+func OSFS() FS {
+	return osFS{}
+}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		// avoid a non-nil File holding a nil *os.File
+		return nil, err
+	}
+	return f, nil
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFS) MkdirAll(name string, perm fs.FileMode) error {
+	return os.MkdirAll(name, perm)
+}
+
+func (osFS) Rename(oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFS) Chmod(name string, mode fs.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (osFS) Chown(name string, uid, gid int) error {
+	return os.Chown(name, uid, gid)
+}
+
+func (osFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (osFS) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+func (osFS) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+// ReadOnlyFS returns a read-only FS backed by fsys, e.g. an embed.FS.
+// Absolute names are looked up relative to the root of fsys.
+// The methods which modify the file system fail with fs.ErrPermission.
+func ReadOnlyFS(fsys fs.FS) FS {
+	return readOnlyFS{fsys: fsys}
+}
+
+type readOnlyFS struct {
+	fsys fs.FS
+}
+
+func (r readOnlyFS) Open(name string) (fs.File, error) {
+	return r.fsys.Open(cleanName(name))
+}
+
+func (r readOnlyFS) OpenFile(name string, flag int, _ fs.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	f, err := r.fsys.Open(cleanName(name))
+	if err != nil {
+		return nil, err
+	}
+	return readOnlyFile{File: f, name: name}, nil
+}
+
+func (r readOnlyFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(r.fsys, cleanName(name))
+}
+
+func (r readOnlyFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(r.fsys, cleanName(name))
+}
+
+func (readOnlyFS) MkdirAll(name string, _ fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrPermission}
+}
+
+func (readOnlyFS) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrPermission}
+}
+
+func (readOnlyFS) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+}
+
+func (readOnlyFS) Chmod(name string, _ fs.FileMode) error {
+	return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrPermission}
+}
+
+func (readOnlyFS) Chown(name string, _, _ int) error {
+	return &fs.PathError{Op: "chown", Path: name, Err: fs.ErrPermission}
+}
+
+// readOnlyFile is a File which can't be written.
+type readOnlyFile struct {
+	fs.File
+	name string
+}
+
+// Name returns the name of the file as presented to OpenFile.
+func (f readOnlyFile) Name() string {
+	return f.name
+}
+
+func (f readOnlyFile) Write([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+}
+
+// cleanName converts name to a valid fs.FS name, e.g. "/a/../b/" to "b".
+func cleanName(name string) string {
+	name = path.Clean("/" + filepath.ToSlash(name))
+	if name == "/" {
+		return "."
+	}
+	return name[1:]
+}
@@ -0,0 +1,333 @@
+package yevna
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MemFS is an in-memory FS, e.g. for tests.
+// Absolute and relative names share the same namespace, "/a/b" and "a/b" are the same file.
+// It is safe for concurrent use.
+type MemFS struct {
+	mu    sync.RWMutex
+	nodes map[string]*memNode
+}
+
+// memNode is a file or a directory of MemFS.
+type memNode struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{nodes: map[string]*memNode{
+		".": {mode: fs.ModeDir | 0755, modTime: time.Now()},
+	}}
+}
+
+// info returns the fs.FileInfo of the node, the caller must hold the lock.
+func (n *memNode) info(name string) fs.FileInfo {
+	return &memInfo{
+		name:    path.Base(name),
+		size:    int64(len(n.data)),
+		mode:    n.mode,
+		modTime: n.modTime,
+	}
+}
+
+// parent returns the parent directory of the node named key, the caller must hold the lock.
+func (m *MemFS) parent(op, name, key string) (*memNode, error) {
+	p, ok := m.nodes[path.Dir(key)]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !p.mode.IsDir() {
+		return nil, &fs.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+	}
+	return p, nil
+}
+
+func (m *MemFS) Open(name string) (fs.File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := cleanName(name)
+	n, ok := m.nodes[key]
+	switch {
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		if _, err := m.parent("open", name, key); err != nil {
+			return nil, err
+		}
+		n = &memNode{mode: perm.Perm(), modTime: time.Now()}
+		m.nodes[key] = n
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if n.mode.IsDir() && writable {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	}
+	if flag&os.O_TRUNC != 0 && writable {
+		n.data = nil
+		n.modTime = time.Now()
+	}
+	return &memFile{
+		fs:       m,
+		node:     n,
+		name:     name,
+		key:      key,
+		readable: flag&os.O_WRONLY == 0,
+		writable: writable,
+		append:   flag&os.O_APPEND != 0,
+	}, nil
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n, ok := m.nodes[cleanName(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return n.info(cleanName(name)), nil
+}
+
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.readDir(name)
+}
+
+// readDir returns the entries of the directory sorted by name, the caller must hold the lock.
+func (m *MemFS) readDir(name string) ([]fs.DirEntry, error) {
+	key := cleanName(name)
+	n, ok := m.nodes[key]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	var entries []fs.DirEntry
+	for k, child := range m.nodes {
+		if k != "." && path.Dir(k) == key {
+			entries = append(entries, fs.FileInfoToDirEntry(child.info(k)))
+		}
+	}
+	slices.SortFunc(
+		entries,
+		func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) },
+	)
+	return entries, nil
+}
+
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := cleanName(name)
+	if key == "." {
+		return nil
+	}
+	dir := ""
+	for _, seg := range strings.Split(key, "/") {
+		dir = path.Join(dir, seg)
+		n, ok := m.nodes[dir]
+		if !ok {
+			m.nodes[dir] = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+			continue
+		}
+		if !n.mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldKey, newKey := cleanName(oldname), cleanName(newname)
+	n, ok := m.nodes[oldKey]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+	if _, err := m.parent("rename", newname, newKey); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err.(*fs.PathError).Err}
+	}
+	if oldKey == newKey {
+		return nil
+	}
+	if target, ok := m.nodes[newKey]; ok && target.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+
+	moved := map[string]*memNode{newKey: n}
+	delete(m.nodes, oldKey)
+	if n.mode.IsDir() {
+		for k, child := range m.nodes {
+			if strings.HasPrefix(k, oldKey+"/") {
+				moved[newKey+k[len(oldKey):]] = child
+				delete(m.nodes, k)
+			}
+		}
+	}
+	for k, child := range moved {
+		m.nodes[k] = child
+	}
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := cleanName(name)
+	n, ok := m.nodes[key]
+	if !ok || key == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if n.mode.IsDir() {
+		if entries, _ := m.readDir(name); len(entries) != 0 {
+			return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+		}
+	}
+	delete(m.nodes, key)
+	return nil
+}
+
+func (m *MemFS) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, ok := m.nodes[cleanName(name)]
+	if !ok {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrNotExist}
+	}
+	n.mode = n.mode.Type() | mode.Perm()
+	return nil
+}
+
+// Chown only checks that the file exists, MemFS doesn't keep owners.
+func (m *MemFS) Chown(name string, _, _ int) error {
+	_, err := m.Stat(name)
+	return err
+}
+
+// memFile is a File opened by MemFS.
+type memFile struct {
+	fs       *MemFS
+	node     *memNode
+	name     string
+	key      string
+	offset   int
+	readable bool
+	writable bool
+	append   bool
+	dirRead  int
+}
+
+// Name returns the name of the file as presented to OpenFile.
+func (f *memFile) Name() string {
+	return f.name
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	f.fs.mu.RLock()
+	defer f.fs.mu.RUnlock()
+	return f.node.info(f.key), nil
+}
+
+func (f *memFile) Read(b []byte) (int, error) {
+	f.fs.mu.RLock()
+	defer f.fs.mu.RUnlock()
+	if !f.readable || f.node.mode.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrPermission}
+	}
+	if f.offset >= len(f.node.data) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.node.data[f.offset:])
+	f.offset += n
+	return n, nil
+}
+
+func (f *memFile) Write(b []byte) (int, error) {
+	if !f.writable {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+	}
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.append {
+		f.offset = len(f.node.data)
+	}
+	if end := f.offset + len(b); end > len(f.node.data) {
+		f.node.data = append(f.node.data, make([]byte, end-len(f.node.data))...)
+	}
+	n := copy(f.node.data[f.offset:], b)
+	f.offset += n
+	f.node.modTime = time.Now()
+	return n, nil
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (f *memFile) ReadDir(count int) ([]fs.DirEntry, error) {
+	f.fs.mu.RLock()
+	entries, err := f.fs.readDir(f.key)
+	f.fs.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entries = entries[min(f.dirRead, len(entries)):]
+	if count > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		entries = entries[:min(count, len(entries))]
+	}
+	f.dirRead += len(entries)
+	return entries, nil
+}
+
+func (f *memFile) Sync() error {
+	return nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+// memInfo is the fs.FileInfo of MemFS.
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *memInfo) Name() string       { return i.name }
+func (i *memInfo) Size() int64        { return i.size }
+func (i *memInfo) Mode() fs.FileMode  { return i.mode }
+func (i *memInfo) ModTime() time.Time { return i.modTime }
+func (i *memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memInfo) Sys() any           { return nil }
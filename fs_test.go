@@ -0,0 +1,139 @@
+package yevna_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"testing/fstest"
+
+	"github.com/tlipoca9/yevna"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FS", func() {
+	y := yevna.New()
+
+	Context("MemFS", func() {
+		It("writes and reads files", func(ctx context.Context) {
+			fsys := yevna.NewMemFS()
+			var got string
+			err := y.Run(
+				ctx,
+				yevna.WithFS(fsys),
+				yevna.Input("a: 1\n"),
+				yevna.WriteFile("etc/app/config.yaml").MkdirAll(true).WithMode(0600),
+				yevna.AppendFile("etc/app/config.yaml"),
+				yevna.OpenFile("etc/app/config.yaml"),
+				yevna.ToStr(),
+				yevna.Output(&got),
+			)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal("a: 1\na: 1\n"))
+			Expect(fs.ReadFile(fsys, "etc/app/config.yaml")).To(Equal([]byte("a: 1\na: 1\n")))
+
+			info, err := fsys.Stat("/etc/app/config.yaml")
+			Expect(err).To(BeNil())
+			Expect(info.Mode()).To(Equal(fs.FileMode(0600)))
+		})
+
+		It("replaces files atomically with backup", func(ctx context.Context) {
+			fsys := yevna.NewMemFS()
+			Expect(fsys.MkdirAll("etc", 0755)).To(Succeed())
+			err := y.Run(
+				ctx,
+				yevna.WithFS(fsys),
+				yevna.Input("old"),
+				yevna.WriteFile("etc/nginx.conf"),
+			)
+			Expect(err).To(BeNil())
+
+			err = y.Run(
+				ctx,
+				yevna.WithFS(fsys),
+				yevna.Input("new"),
+				yevna.WriteFile("etc/nginx.conf").Atomic(true).Backup(true),
+			)
+			Expect(err).To(BeNil())
+			Expect(fs.ReadFile(fsys, "etc/nginx.conf")).To(Equal([]byte("new")))
+			Expect(fs.ReadFile(fsys, "etc/nginx.conf.bak")).To(Equal([]byte("old")))
+
+			entries, err := fsys.ReadDir("etc")
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(2))
+		})
+
+		It("walks files", func(ctx context.Context) {
+			fsys := yevna.NewMemFS()
+			var got any
+			err := y.Run(
+				ctx,
+				yevna.WithFS(fsys),
+				yevna.Input("x"),
+				yevna.WriteFile(
+					"deploy/base.yaml",
+					"deploy/prod/values.yaml",
+					"deploy/prod/values.json",
+				).MkdirAll(true),
+				yevna.Chdir("deploy"),
+				yevna.Glob("**/*.yaml").Open(true).ForEach(
+					yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+						b, err := io.ReadAll(in.(io.Reader))
+						return in.(interface{ Name() string }).Name() + "=" + string(b), err
+					}),
+				),
+				yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+					got = in
+					return in, nil
+				}),
+			)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]any{"deploy/base.yaml=x", "deploy/prod/values.yaml=x"}))
+		})
+
+		It("reports errors like os", func() {
+			fsys := yevna.NewMemFS()
+			_, err := fsys.Stat("missing")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+
+			Expect(fsys.MkdirAll("a/b", 0755)).To(Succeed())
+			Expect(fsys.Remove("a")).NotTo(Succeed())
+			Expect(fsys.Rename("a", "c")).To(Succeed())
+			_, err = fsys.Stat("c/b")
+			Expect(err).To(BeNil())
+			_, err = fsys.Stat("a")
+			Expect(err).To(MatchError(fs.ErrNotExist))
+		})
+	})
+
+	Context("ReadOnlyFS", func() {
+		fsys := yevna.ReadOnlyFS(fstest.MapFS{
+			"templates/app.tmpl": {Data: []byte("name: {{ .name }}\n")},
+		})
+
+		It("reads templates", func(ctx context.Context) {
+			var buf bytes.Buffer
+			err := y.Run(
+				ctx,
+				yevna.WithFS(fsys),
+				yevna.Input(map[string]any{"name": "web"}),
+				yevna.TemplateFile("templates/app.tmpl"),
+				yevna.Output(&buf),
+			)
+			Expect(err).To(BeNil())
+			Expect(buf.String()).To(Equal("name: web\n"))
+		})
+
+		It("fails to write", func(ctx context.Context) {
+			err := y.Run(
+				ctx,
+				yevna.WithFS(fsys),
+				yevna.Input("x"),
+				yevna.WriteFile("templates/app.tmpl"),
+			)
+			Expect(err).To(MatchError(fs.ErrPermission))
+		})
+	})
+})
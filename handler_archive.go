@@ -0,0 +1,583 @@
+package yevna
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/tlipoca9/yevna/utils"
+)
+
+// ArchiveHandler is a Handler that archives files and directories using Context.FS.
+// The paths are relative to the working directory if they are relative,
+// and they are stored with the same relative names in the archive.
+//
+// It streams the archive as io.Reader to next handler.
+type ArchiveHandler struct {
+	zip           bool
+	paths         []string
+	gzip          bool
+	deterministic bool
+	modTime       time.Time
+}
+
+// Tar returns a new ArchiveHandler which creates a tar archive of the paths.
+func Tar(paths ...string) *ArchiveHandler {
+	if len(paths) == 0 {
+		panic("no path specified")
+	}
+	return &ArchiveHandler{paths: paths}
+}
+
+// Zip returns a new ArchiveHandler which creates a zip archive of the paths.
+func Zip(paths ...string) *ArchiveHandler {
+	h := Tar(paths...)
+	h.zip = true
+	return h
+}
+
+// Gzip sets whether to compress the tar archive with gzip, it has no effect on Zip.
+func (h *ArchiveHandler) Gzip(gz bool) *ArchiveHandler {
+	h.gzip = gz
+	return h
+}
+
+// Deterministic sets the deterministic mode for reproducible builds.
+// If deterministic is true, the timestamps are set to SOURCE_DATE_EPOCH,
+// or 1980-01-01 if it is not set, and the owners are cleared.
+func (h *ArchiveHandler) Deterministic(deterministic bool) *ArchiveHandler {
+	h.deterministic = deterministic
+	return h
+}
+
+// WithModTime sets the timestamps of all files, it enables the deterministic mode.
+func (h *ArchiveHandler) WithModTime(t time.Time) *ArchiveHandler {
+	h.deterministic = true
+	h.modTime = t
+	return h
+}
+
+// archiveEntry is a file to be archived.
+type archiveEntry struct {
+	name string
+	path string
+	info fs.FileInfo
+	link string
+}
+
+// Handle implements Handler.
+func (h *ArchiveHandler) Handle(c *Context, _ any) (any, error) {
+	fsys := c.FS()
+	entries, err := h.entries(c, fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return pipe(c, func(w io.Writer) error {
+		if h.zip {
+			return h.writeZip(fsys, entries, w)
+		}
+		return h.writeTar(fsys, entries, w)
+	})
+}
+
+// entries walks the paths and returns the entries in lexical order of each path.
+func (h *ArchiveHandler) entries(c *Context, fsys FS) ([]archiveEntry, error) {
+	var entries []archiveEntry
+	for _, p := range h.paths {
+		root := c.path(p)
+		base := archiveName(p)
+		err := fs.WalkDir(fsys, root, func(full string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, full)
+			if err != nil {
+				return err
+			}
+			name := path.Join(base, filepath.ToSlash(rel))
+			if name == "." {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			e := archiveEntry{name: name, path: full, info: info}
+			if info.Mode()&fs.ModeSymlink != 0 {
+				sfs, ok := fsys.(SymlinkFS)
+				if !ok {
+					return errors.Newf("symlink %s is not supported by %T", full, fsys)
+				}
+				if e.link, err = sfs.Readlink(full); err != nil {
+					return err
+				}
+			}
+			entries = append(entries, e)
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to archive %s", p)
+		}
+	}
+	return entries, nil
+}
+
+// mtime returns the timestamp of the deterministic mode.
+func (h *ArchiveHandler) mtime() time.Time {
+	if !h.modTime.IsZero() {
+		return h.modTime
+	}
+	if s := os.Getenv("SOURCE_DATE_EPOCH"); s != "" {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(n, 0).UTC()
+		}
+	}
+	return time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (h *ArchiveHandler) writeTar(fsys FS, entries []archiveEntry, w io.Writer) error {
+	if !h.gzip {
+		return h.tar(fsys, entries, w)
+	}
+	gw := gzip.NewWriter(w)
+	if err := h.tar(fsys, entries, gw); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func (h *ArchiveHandler) tar(fsys FS, entries []archiveEntry, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr, err := tar.FileInfoHeader(e.info, e.link)
+		if err != nil {
+			return errors.Wrapf(err, "failed to archive %s", e.path)
+		}
+		hdr.Name = e.name
+		if e.info.IsDir() {
+			hdr.Name += "/"
+		}
+		if h.deterministic {
+			hdr.ModTime = h.mtime().Truncate(time.Second)
+			hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+			hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		}
+		if err = tw.WriteHeader(hdr); err != nil {
+			return errors.Wrapf(err, "failed to archive %s", e.path)
+		}
+		if e.info.Mode().IsRegular() {
+			if err = copyFile(fsys, e.path, tw); err != nil {
+				return errors.Wrapf(err, "failed to archive %s", e.path)
+			}
+		}
+	}
+	return tw.Close()
+}
+
+func (h *ArchiveHandler) writeZip(fsys FS, entries []archiveEntry, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		hdr, err := zip.FileInfoHeader(e.info)
+		if err != nil {
+			return errors.Wrapf(err, "failed to archive %s", e.path)
+		}
+		hdr.Name = e.name
+		hdr.Method = zip.Deflate
+		if e.info.IsDir() {
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+		}
+		if h.deterministic {
+			hdr.Modified = h.mtime().Truncate(time.Second)
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return errors.Wrapf(err, "failed to archive %s", e.path)
+		}
+		switch {
+		case e.link != "":
+			_, err = io.WriteString(fw, e.link)
+		case e.info.Mode().IsRegular():
+			err = copyFile(fsys, e.path, fw)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to archive %s", e.path)
+		}
+	}
+	return zw.Close()
+}
+
+// copyFile copies the content of the file to w.
+func copyFile(fsys FS, name string, w io.Writer) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// archiveName returns the name of p in the archive, the leading "/" and "../" are removed.
+func archiveName(p string) string {
+	name := path.Clean(filepath.ToSlash(p))
+	name = strings.TrimLeft(name, "/")
+	for name == ".." || strings.HasPrefix(name, "../") {
+		name = strings.TrimPrefix(strings.TrimPrefix(name, ".."), "/")
+	}
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// ExtractHandler is a Handler that extracts an archive into a directory using Context.FS.
+// The destination is relative to the working directory if it is relative.
+// The entries which would be written outside the destination,
+// including through symlinks, are rejected.
+//
+// It sends the paths of the extracted files as []string to next handler.
+type ExtractHandler struct {
+	zip  bool
+	dest string
+}
+
+// Untar returns a new ExtractHandler which extracts a tar archive into dest,
+// the archive is optionally gzip compressed.
+func Untar(dest string) *ExtractHandler {
+	return &ExtractHandler{dest: dest}
+}
+
+// Unzip returns a new ExtractHandler which extracts a zip archive into dest.
+func Unzip(dest string) *ExtractHandler {
+	return &ExtractHandler{zip: true, dest: dest}
+}
+
+// Handle implements Handler.
+func (h *ExtractHandler) Handle(c *Context, in any) (any, error) {
+	x := &extractor{
+		fsys:     c.FS(),
+		root:     c.path(h.dest),
+		dest:     h.dest,
+		dirModes: make(map[string]fs.FileMode),
+	}
+	if err := x.fsys.MkdirAll(x.root, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create destination")
+	}
+
+	var err error
+	if h.zip {
+		err = h.unzip(x, in)
+	} else {
+		err = h.untar(x, in)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = x.close(); err != nil {
+		return nil, err
+	}
+	return x.paths, nil
+}
+
+func (h *ExtractHandler) untar(x *extractor, in any) error {
+	r, err := utils.Reader(in)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return errors.Wrap(err, "failed to read gzip")
+		}
+		defer gr.Close()
+		r = gr
+	} else {
+		r = br
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read tar")
+		}
+
+		mode := hdr.FileInfo().Mode()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = x.dir(hdr.Name, mode)
+		case tar.TypeReg, tar.TypeRegA:
+			err = x.file(hdr.Name, mode, tr)
+		case tar.TypeSymlink:
+			err = x.symlink(hdr.Name, hdr.Linkname)
+		case tar.TypeLink:
+			err = x.hardlink(hdr.Name, hdr.Linkname, mode)
+		default:
+			// devices and fifos are not extracted
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to extract %s", hdr.Name)
+		}
+	}
+}
+
+func (h *ExtractHandler) unzip(x *extractor, in any) error {
+	ra, size, err := readerAt(in)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return errors.Wrap(err, "failed to read zip")
+	}
+
+	for _, f := range zr.File {
+		if err = h.unzipFile(x, f); err != nil {
+			return errors.Wrapf(err, "failed to extract %s", f.Name)
+		}
+	}
+	return nil
+}
+
+// readerAt returns the input as io.ReaderAt with its size, as zip archives are read from the end.
+// The input is used directly if it is []byte, string or an io.ReaderAt with a known size,
+// e.g. *os.File, otherwise it is read into memory.
+func readerAt(in any) (io.ReaderAt, int64, error) {
+	switch v := in.(type) {
+	case []byte:
+		return bytes.NewReader(v), int64(len(v)), nil
+	case string:
+		return strings.NewReader(v), int64(len(v)), nil
+	case io.ReaderAt:
+		switch s := v.(type) {
+		case interface{ Size() int64 }:
+			return v, s.Size(), nil
+		case interface{ Stat() (fs.FileInfo, error) }:
+			if info, err := s.Stat(); err == nil && info.Mode().IsRegular() {
+				return v, info.Size(), nil
+			}
+		}
+	}
+
+	b, err := readAll(in)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(b), int64(len(b)), nil
+}
+
+func (h *ExtractHandler) unzipFile(x *extractor, f *zip.File) error {
+	mode := f.Mode()
+	if mode.IsDir() {
+		return x.dir(f.Name, mode)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if mode&fs.ModeSymlink != 0 {
+		target, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		return x.symlink(f.Name, string(target))
+	}
+	return x.file(f.Name, mode, rc)
+}
+
+// extractor writes the entries of an archive under root.
+type extractor struct {
+	fsys     FS
+	root     string
+	dest     string
+	paths    []string
+	dirModes map[string]fs.FileMode
+}
+
+// resolve checks the name of an entry and returns its path in the file system.
+func (x *extractor) resolve(name string) (string, error) {
+	local := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if !filepath.IsLocal(local) {
+		return "", errors.Newf("illegal path %q in archive", name)
+	}
+	return filepath.Join(x.root, local), nil
+}
+
+// prepare creates the parent directories of full, and removes the symlink at full,
+// so that the entry is never written through a symlink.
+func (x *extractor) prepare(full string) error {
+	sfs, ok := x.fsys.(SymlinkFS)
+	if !ok {
+		return x.fsys.MkdirAll(filepath.Dir(full), 0755)
+	}
+	if err := x.parents(sfs, full, true); err != nil {
+		return err
+	}
+	if info, err := sfs.Lstat(full); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return sfs.Remove(full)
+	}
+	return nil
+}
+
+// parents checks the parent directories of full under root one by one,
+// and creates the missing ones if mkdir is true.
+// It fails if any of them is a symlink, as MkdirAll and OpenFile follow symlinks,
+// and a chain of symlinks which are legal by themselves may point outside root.
+func (x *extractor) parents(sfs SymlinkFS, full string, mkdir bool) error {
+	rel, err := filepath.Rel(x.root, filepath.Dir(full))
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	dir := x.root
+	for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, elem)
+		info, err := sfs.Lstat(dir)
+		switch {
+		case mkdir && errors.Is(err, fs.ErrNotExist):
+			if err = sfs.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case info.Mode()&fs.ModeSymlink != 0:
+			return errors.Newf("illegal path through symlink %s", dir)
+		}
+	}
+	return nil
+}
+
+func (x *extractor) dir(name string, mode fs.FileMode) error {
+	full, err := x.resolve(name)
+	if err != nil {
+		return err
+	}
+	if err = x.prepare(full); err != nil {
+		return err
+	}
+	if err = x.fsys.MkdirAll(full, 0755); err != nil {
+		return err
+	}
+	// apply the mode at last, so that read-only directories can be filled
+	x.dirModes[full] = mode.Perm()
+	return nil
+}
+
+func (x *extractor) file(name string, mode fs.FileMode, r io.Reader) error {
+	full, err := x.resolve(name)
+	if err != nil {
+		return err
+	}
+	if err = x.prepare(full); err != nil {
+		return err
+	}
+
+	f, err := x.fsys.OpenFile(full, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	// OpenFile is affected by umask
+	if err = x.fsys.Chmod(full, mode.Perm()); err != nil {
+		return err
+	}
+	x.paths = append(x.paths, filepath.Join(x.dest, filepath.FromSlash(name)))
+	return nil
+}
+
+func (x *extractor) symlink(name, target string) error {
+	full, err := x.resolve(name)
+	if err != nil {
+		return err
+	}
+	if path.IsAbs(target) ||
+		!filepath.IsLocal(filepath.FromSlash(path.Join(path.Dir(name), target))) {
+		return errors.Newf("illegal link %q -> %q in archive", name, target)
+	}
+	sfs, ok := x.fsys.(SymlinkFS)
+	if !ok {
+		return errors.Newf("symlink is not supported by %T", x.fsys)
+	}
+	if err = x.prepare(full); err != nil {
+		return err
+	}
+	if err = sfs.Remove(full); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err = sfs.Symlink(filepath.FromSlash(target), full); err != nil {
+		return err
+	}
+	x.paths = append(x.paths, filepath.Join(x.dest, filepath.FromSlash(name)))
+	return nil
+}
+
+// hardlink copies the previously extracted target, as FS doesn't support hard links.
+func (x *extractor) hardlink(name, target string, mode fs.FileMode) error {
+	src, err := x.resolve(target)
+	if err != nil {
+		return errors.Newf("illegal link %q -> %q in archive", name, target)
+	}
+	if sfs, ok := x.fsys.(SymlinkFS); ok {
+		if err = x.parents(sfs, src, false); err != nil {
+			return err
+		}
+		info, err := sfs.Lstat(src)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return errors.Newf("illegal link %q -> %q in archive", name, target)
+		}
+	}
+	b, err := fs.ReadFile(x.fsys, src)
+	if err != nil {
+		return err
+	}
+	return x.file(name, mode, bytes.NewReader(b))
+}
+
+// close applies the modes of directories, the directories replaced by symlinks are skipped.
+func (x *extractor) close() error {
+	sfs, _ := x.fsys.(SymlinkFS)
+	for dir, mode := range x.dirModes {
+		if sfs != nil {
+			if info, err := sfs.Lstat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+		}
+		if err := x.fsys.Chmod(dir, mode); err != nil {
+			return errors.Wrapf(err, "failed to chmod %s", dir)
+		}
+	}
+	return nil
+}
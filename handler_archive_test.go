@@ -0,0 +1,160 @@
+package yevna_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/tlipoca9/yevna"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - Archive", func() {
+	var (
+		y   = yevna.New()
+		dir string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		src := filepath.Join(dir, "src", "release")
+		Expect(os.MkdirAll(filepath.Join(src, "bin"), 0755)).To(Succeed())
+		Expect(
+			os.WriteFile(filepath.Join(src, "bin", "app"), []byte("#!/bin/sh\necho app\n"), 0755),
+		).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(src, "README"), []byte("readme"), 0600)).To(Succeed())
+		Expect(os.Symlink("bin/app", filepath.Join(src, "app"))).To(Succeed())
+	})
+
+	archive := func(ctx context.Context, h yevna.Handler) []byte {
+		var buf bytes.Buffer
+		err := y.Run(ctx, yevna.Chdir(filepath.Join(dir, "src")), h, yevna.Tee(&buf))
+		Expect(err).To(BeNil())
+		return buf.Bytes()
+	}
+
+	extract := func(ctx context.Context, b []byte, h yevna.Handler) []string {
+		var got []string
+		err := y.Run(ctx, yevna.Chdir(dir), yevna.Input(b), h, yevna.Output(&got))
+		Expect(err).To(BeNil())
+		return got
+	}
+
+	expectExtracted := func(dest string) {
+		Expect(os.ReadFile(filepath.Join(dest, "release", "README"))).To(Equal([]byte("readme")))
+		info, err := os.Stat(filepath.Join(dest, "release", "bin", "app"))
+		Expect(err).To(BeNil())
+		Expect(info.Mode().Perm()).To(Equal(fs.FileMode(0755)))
+		info, err = os.Stat(filepath.Join(dest, "release", "README"))
+		Expect(err).To(BeNil())
+		Expect(info.Mode().Perm()).To(Equal(fs.FileMode(0600)))
+		Expect(os.Readlink(filepath.Join(dest, "release", "app"))).To(Equal("bin/app"))
+	}
+
+	It("tars and untars", func(ctx context.Context) {
+		b := archive(ctx, yevna.Tar("release").Gzip(true))
+		got := extract(ctx, b, yevna.Untar("out"))
+		Expect(got).To(Equal(
+			[]string{"out/release/README", "out/release/app", "out/release/bin/app"},
+		))
+		expectExtracted(filepath.Join(dir, "out"))
+	})
+
+	It("zips and unzips", func(ctx context.Context) {
+		b := archive(ctx, yevna.Zip("release"))
+		got := extract(ctx, b, yevna.Unzip("out"))
+		Expect(got).To(Equal(
+			[]string{"out/release/README", "out/release/app", "out/release/bin/app"},
+		))
+		expectExtracted(filepath.Join(dir, "out"))
+
+		Expect(os.WriteFile(filepath.Join(dir, "release.zip"), b, 0644)).To(Succeed())
+		err := y.Run(ctx, yevna.Chdir(dir), yevna.OpenFile("release.zip"), yevna.Unzip("file"))
+		Expect(err).To(BeNil())
+		expectExtracted(filepath.Join(dir, "file"))
+	})
+
+	It("creates reproducible archives", func(ctx context.Context) {
+		a := archive(ctx, yevna.Tar("release").Deterministic(true))
+		Expect(
+			os.Chtimes(
+				filepath.Join(dir, "src", "release", "README"),
+				time.Now(),
+				time.Now().Add(time.Hour),
+			),
+		).To(Succeed())
+		Expect(archive(ctx, yevna.Tar("release").Deterministic(true))).To(Equal(a))
+
+		a = archive(ctx, yevna.Zip("release").WithModTime(time.Unix(1700000000, 0)))
+		Expect(
+			archive(ctx, yevna.Zip("release").WithModTime(time.Unix(1700000000, 0))),
+		).To(Equal(a))
+	})
+
+	It("stops archiving if the output isn't read", func(ctx context.Context) {
+		before := runtime.NumGoroutine()
+		for range 10 {
+			Expect(
+				y.Run(ctx, yevna.Chdir(filepath.Join(dir, "src")), yevna.Tar("release")),
+			).To(Succeed())
+		}
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", before))
+	})
+
+	It("fails if the path doesn't exist", func(ctx context.Context) {
+		err := y.Run(ctx, yevna.Chdir(dir), yevna.Tar("missing"))
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+
+	It("rejects path traversal", func(ctx context.Context) {
+		tarball := func(hdrs ...*tar.Header) []byte {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for _, hdr := range hdrs {
+				content := ""
+				if hdr.Typeflag == tar.TypeReg {
+					content = "evil"
+				}
+				hdr.Size = int64(len(content))
+				Expect(tw.WriteHeader(hdr)).To(Succeed())
+				_, err := io.WriteString(tw, content)
+				Expect(err).To(BeNil())
+			}
+			Expect(tw.Close()).To(Succeed())
+			return buf.Bytes()
+		}
+
+		for _, b := range [][]byte{
+			tarball(&tar.Header{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644}),
+			tarball(&tar.Header{Name: "/tmp/evil", Typeflag: tar.TypeReg, Mode: 0644}),
+			tarball(&tar.Header{Name: "a/evil", Typeflag: tar.TypeSymlink, Linkname: "../../evil"}),
+			tarball(&tar.Header{Name: "evil", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}),
+			// each link is legal by itself, but d/up/up2 points to the parent of out
+			tarball(
+				&tar.Header{Name: "d/", Typeflag: tar.TypeDir, Mode: 0755},
+				&tar.Header{Name: "d/up", Typeflag: tar.TypeSymlink, Linkname: ".."},
+				&tar.Header{Name: "d/up/up2", Typeflag: tar.TypeSymlink, Linkname: ".."},
+				&tar.Header{Name: "d/up/up2/evil", Typeflag: tar.TypeReg, Mode: 0644},
+			),
+			tarball(
+				&tar.Header{Name: "d/", Typeflag: tar.TypeDir, Mode: 0755},
+				&tar.Header{Name: "d/up", Typeflag: tar.TypeSymlink, Linkname: ".."},
+				&tar.Header{Name: "d/up/up2", Typeflag: tar.TypeSymlink, Linkname: ".."},
+				&tar.Header{Name: "evil", Typeflag: tar.TypeLink, Linkname: "d/up/up2/out/d/up"},
+			),
+		} {
+			err := y.Run(ctx, yevna.Chdir(dir), yevna.Input(b), yevna.Untar("out"))
+			Expect(err).To(MatchError(ContainSubstring("illegal")))
+		}
+		_, err := os.Lstat(filepath.Join(dir, "evil"))
+		Expect(err).To(MatchError(fs.ErrNotExist))
+	})
+})
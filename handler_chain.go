@@ -0,0 +1,85 @@
+package yevna
+
+import (
+	"bytes"
+	"io"
+	"maps"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ChainHandler is a Handler that runs a group of handlers as a sub-pipeline.
+// The group runs in a sub-context which inherits the working directory, silent flag, values and FS,
+// and Context.Next inside the group returns at the end of the group
+// instead of continuing into the outer chain.
+//
+// It sends the output of the group to next handler.
+// If the output is an io.Reader, it is read into *bytes.Buffer at the end of the group,
+// as the resource behind it, e.g. the stdout of Exec or the file of OpenFile,
+// is released when the group returns.
+type ChainHandler struct {
+	name     string
+	handlers HandlersChain
+	scoped   bool
+}
+
+// Chain returns a new ChainHandler which runs the handlers.
+func Chain(handlers ...Handler) *ChainHandler {
+	return &ChainHandler{
+		handlers: append(HandlersChain(handlers).Copy(), HandlerFunc(bufferOutput)),
+	}
+}
+
+// bufferOutput reads the io.Reader output of the group into memory.
+func bufferOutput(_ *Context, in any) (any, error) {
+	r, ok := in.(io.Reader)
+	if !ok {
+		return in, nil
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		return nil, errors.Wrap(err, "failed to read")
+	}
+	return &buf, nil
+}
+
+// Scoped sets whether to discard the changes of the group to the Context,
+// e.g. Chdir, Silent and Value inside the group don't affect the outer chain.
+// By default, the changes are kept after the group returns.
+func (h *ChainHandler) Scoped(scoped bool) *ChainHandler {
+	h.scoped = scoped
+	return h
+}
+
+// Named sets the name of the group, which is added to the errors of the group.
+func (h *ChainHandler) Named(name string) *ChainHandler {
+	h.name = name
+	return h
+}
+
+// Handle implements Handler.
+func (h *ChainHandler) Handle(c *Context, in any) (any, error) {
+	cc := c.fork(h.handlers)
+	out, err := cc.Next(in)
+	if err != nil {
+		if h.name != "" {
+			return nil, errors.Wrap(err, h.name)
+		}
+		return nil, err
+	}
+
+	if !h.scoped {
+		c.workdir, c.silent, c.fsys = cc.workdir, cc.silent, cc.fsys
+		// the values may be accessed by streaming goroutines of the outer chain,
+		// e.g. the onEOF of Hash
+		c.mu.Lock()
+		cc.mu.RLock()
+		if c.values == nil && len(cc.values) > 0 {
+			c.values = make(map[string]any, len(cc.values))
+		}
+		maps.Copy(c.values, cc.values)
+		cc.mu.RUnlock()
+		c.mu.Unlock()
+	}
+	return out, nil
+}
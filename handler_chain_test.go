@@ -0,0 +1,140 @@
+package yevna_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/tlipoca9/yevna"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - Chain", func() {
+	y := yevna.New()
+
+	upper := yevna.Chain(
+		yevna.ToStr(),
+		yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+			return strings.ToUpper(in.(string)), nil
+		}),
+	)
+
+	It("runs the group and returns to the outer chain", func(ctx context.Context) {
+		var steps []string
+		step := func(name string) yevna.Handler {
+			return yevna.HandlerFunc(func(c *yevna.Context, in any) (any, error) {
+				steps = append(steps, name)
+				return in, nil
+			})
+		}
+
+		var got string
+		err := y.Run(
+			ctx,
+			yevna.Input("hello"),
+			yevna.Chain(step("a"), upper, step("b")),
+			step("c"),
+			upper,
+			yevna.Output(&got),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("HELLO"))
+		Expect(steps).To(Equal([]string{"a", "b", "c"}))
+	})
+
+	It("returns to the outer chain when the group calls Next", func(ctx context.Context) {
+		var got string
+		err := y.Run(
+			ctx,
+			yevna.Input("hello"),
+			yevna.Chain(yevna.HandlerFunc(func(c *yevna.Context, in any) (any, error) {
+				out, err := c.Next(in)
+				return out.(string) + "!", err
+			}), upper),
+			yevna.Output(&got),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("HELLO!"))
+	})
+
+	It("reads the output of commands and files inside the group", func(ctx context.Context) {
+		var got string
+		err := y.Run(
+			ctx,
+			yevna.Chain(yevna.Exec("echo", "hello")),
+			yevna.ToStr(),
+			yevna.Output(&got),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("hello\n"))
+
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte("a: 1\n"), 0644)).To(Succeed())
+		err = y.Run(ctx, yevna.Chain(yevna.OpenFile(path)), yevna.ToStr(), yevna.Output(&got))
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("a: 1\n"))
+	})
+
+	It("scopes the changes of the Context", func(ctx context.Context) {
+		var workdir, value any
+		inspect := yevna.HandlerFunc(func(c *yevna.Context, in any) (any, error) {
+			workdir, value = c.Workdir(), c.Value("step")
+			return in, nil
+		})
+
+		err := y.Run(ctx, yevna.Chain(yevna.Chdir("/tmp"), yevna.Value("step", "group")), inspect)
+		Expect(err).To(BeNil())
+		Expect(workdir).To(Equal("/tmp"))
+		Expect(value).To(Equal("group"))
+
+		err = y.Run(
+			ctx,
+			yevna.Chdir("/"),
+			yevna.Chain(yevna.Chdir("/tmp"), yevna.Value("step", "group"), inspect).Scoped(true),
+			yevna.HandlerFunc(func(c *yevna.Context, in any) (any, error) {
+				Expect(workdir).To(Equal("/tmp"))
+				Expect(c.Workdir()).To(Equal("/"))
+				Expect(c.Value("step")).To(BeNil())
+				return in, nil
+			}),
+		)
+		Expect(err).To(BeNil())
+	})
+
+	It("keeps the values of the group while the outer chain reads them", func(ctx context.Context) {
+		digest := make(chan any)
+		err := y.Run(
+			ctx,
+			yevna.Input("hello"),
+			yevna.HandlerFunc(func(c *yevna.Context, in any) (any, error) {
+				go func() {
+					for {
+						if v := c.Value(yevna.HashKey); v != nil {
+							digest <- v
+							return
+						}
+						runtime.Gosched()
+					}
+				}()
+				return c.Next(in)
+			}),
+			yevna.Chain(yevna.Hash("sha256"), yevna.ToStr()),
+		)
+		Expect(err).To(BeNil())
+		Eventually(
+			digest,
+		).Should(Receive(Equal("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")))
+	})
+
+	It("names the errors of the group", func(ctx context.Context) {
+		err := y.Run(ctx, yevna.Chain(yevna.HandlerFunc(func(*yevna.Context, any) (any, error) {
+			return nil, errors.New("boom")
+		})).Named("deploy"))
+		Expect(err).To(MatchError("deploy: boom"))
+	})
+})
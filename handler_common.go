@@ -1,12 +1,11 @@
 package yevna
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"sync"
 
 	"github.com/cockroachdb/errors"
 
@@ -80,14 +79,11 @@ func ToStr() Handler {
 }
 
 // Chdir returns a Handler that changes the working directory.
-// It uses IfExists to check if the path exists.
+// It checks that the path exists in Context.FS.
 // It sends input to next handler.
 func Chdir(path string) Handler {
 	return HandlerFunc(func(c *Context, in any) (any, error) {
-		if filepath.IsLocal(path) {
-			path = filepath.Join(c.Workdir(), path)
-		}
-		_, err := os.Stat(path)
+		_, err := c.FS().Stat(c.path(path))
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to change working directory")
 		}
@@ -106,6 +102,24 @@ func Silent(s bool) Handler {
 	})
 }
 
+// WithFS returns a Handler that sets the file system used by the file handlers.
+// It sends original input to next handler.
+func WithFS(fsys FS) Handler {
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		c.FS(fsys)
+		return in, nil
+	})
+}
+
+// Value returns a Handler that sets the value associated with key.
+// It sends original input to next handler.
+func Value(key string, v any) Handler {
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		c.Value(key, v)
+		return in, nil
+	})
+}
+
 // Input returns a Handler that sets the input.
 // It sends the input to next handler.
 func Input(a any) Handler {
@@ -130,21 +144,84 @@ func Output[T any](a *T) Handler {
 	})
 }
 
-// Tee returns a Handler that writes to multiple writers.
-// It uses io.Copy to copy the input to the writers
-// and sends input to next handler.
-func Tee(w ...io.Writer) Handler {
-	return HandlerFunc(func(_ *Context, in any) (any, error) {
-		r, err := utils.Reader(in)
+// TeeHandler is a Handler that writes the input to multiple writers while forwarding it.
+//
+// By default, it streams the input as io.Reader to next handler, and copies it to the writers
+// as next handler reads it, so the memory usage is constant.
+// The rest of the input is copied after next handler returns.
+// If Buffered is set, it copies all input first, and sends it as *bytes.Buffer to next handler.
+type TeeHandler struct {
+	w        []io.Writer
+	buffered bool
+}
+
+// Tee returns a new TeeHandler which writes to the writers.
+func Tee(w ...io.Writer) *TeeHandler {
+	return &TeeHandler{w: w}
+}
+
+// Buffered sets the buffered mode, for next handlers which need to read the input more than once.
+func (h *TeeHandler) Buffered(buffered bool) *TeeHandler {
+	h.buffered = buffered
+	return h
+}
+
+// Handle implements Handler.
+func (h *TeeHandler) Handle(c *Context, in any) (any, error) {
+	r, err := utils.Reader(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.buffered {
+		var buf bytes.Buffer
+		_, err = io.Copy(io.MultiWriter(append(h.w, &buf)...), r)
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrap(err, "failed to copy")
 		}
+		return &buf, nil
+	}
+	return teeNext(c, r, io.MultiWriter(h.w...))
+}
 
-		var buf bytes.Buffer
-		w = append(w, &buf)
-		_, err = io.Copy(io.MultiWriter(w...), r)
-		return &buf, errors.Wrap(err, "failed to copy")
-	})
+// teeNext sends the reader which writes to w what it reads from r to next handler,
+// and copies the rest of r to w after next handler returns.
+func teeNext(c *Context, r io.Reader, w io.Writer) (any, error) {
+	tr := &teeReader{r: io.TeeReader(r, w)}
+	out, err := c.Next(tr)
+	if err != nil {
+		return nil, err
+	}
+	if err = tr.drain(); err != nil {
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+	return out, nil
+}
+
+// teeReader serializes the reads of next handlers and the drain after next handler returns,
+// as next handlers may still read it in another goroutine.
+type teeReader struct {
+	mu      sync.Mutex
+	r       io.Reader
+	drained bool
+}
+
+func (t *teeReader) Read(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.drained {
+		return 0, errStreamClosed
+	}
+	return t.r.Read(p)
+}
+
+// drain reads the rest, the reads after it fail with errStreamClosed.
+func (t *teeReader) drain() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.drained = true
+	_, err := io.Copy(io.Discard, t.r)
+	return err
 }
 
 // Unmarshal returns a Handler that unmarshal the input.
@@ -172,123 +249,117 @@ func Unmarshal[T any](p parser.Parser, v *T) Handler {
 	})
 }
 
-// Marshal returns a Handler that marshal the input.
-func Marshal(m func(any) ([]byte, error), v ...any) Handler {
-	if len(v) > 1 {
-		panic("too many arguments")
-	}
+// UnmarshalAuto returns a Handler that unmarshal the input
+// with the parser chosen by parser.DefaultRegistry.
+// The format is chosen by the file name of the input (e.g. from OpenFile),
+// then by the Content-Type of the input (e.g. the body from HTTP),
+// then by sniffing the content with parser.Detect.
+// It sends v to next handler.
+func UnmarshalAuto[T any](v *T) Handler {
 	return HandlerFunc(func(_ *Context, in any) (any, error) {
-		if len(v) == 1 {
-			in = v[0]
+		var name, contentType string
+		if f, ok := in.(interface{ Name() string }); ok {
+			name = f.Name()
 		}
-		b, err := m(in)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to marshal")
+		if r, ok := in.(interface{ ContentType() string }); ok {
+			contentType = r.ContentType()
 		}
-		return b, nil
-	})
-}
 
-// ForEachLine returns a Handler that applies the callback function to each line.
-func ForEachLine(cb func(i int, line string) string) Handler {
-	return HandlerFunc(func(_ *Context, in any) (any, error) {
-		r, err := utils.Reader(in)
+		b, err := readAll(in)
 		if err != nil {
 			return nil, err
 		}
 
-		var buf bytes.Buffer
-		scanner := bufio.NewScanner(r)
-		scanner.Split(bufio.ScanLines)
-		for i := 0; scanner.Scan(); i++ {
-			line := cb(i, scanner.Text())
-			buf.WriteString(line)
-			buf.WriteByte('\n')
+		f, ok := parser.ByExtension(name)
+		if !ok {
+			f, ok = parser.ByMIME(contentType)
 		}
-		if err := scanner.Err(); err != nil {
-			return nil, errors.Wrap(err, "failed to scan")
+		if !ok {
+			f, ok = parser.Detect(b)
 		}
-
-		return &buf, nil
-	})
-}
-
-// OpenFile returns a Handler that opens a file.
-// It sends input to next handler.
-func OpenFile(path string) Handler {
-	return HandlerFunc(func(c *Context, _ any) (any, error) {
-		if filepath.IsLocal(path) {
-			path = filepath.Join(c.Workdir(), path)
+		if !ok {
+			return nil, errors.New("failed to detect format")
 		}
-		f, err := os.Open(path)
+
+		err = f.Parser.Unmarshal(b, v)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to open file")
+			return nil, errors.Wrapf(err, "failed to unmarshal %s", f.Name)
 		}
 
-		out, err := c.Next(f)
-
-		_ = f.Close()
-
-		return out, err
+		return v, nil
 	})
 }
 
-// WriteFile returns a Handler that writes to a file.
-// If the file does not exist, it will be created.
-// If the file exists, it will be truncated.
-// If you want to append to a file, use AppendFile instead.
-// It sends input to next handler.
-func WriteFile(path ...string) Handler {
-	return writeFileWithFlag(os.O_WRONLY|os.O_CREATE|os.O_TRUNC, path...)
-}
-
-// AppendFile returns a Handler that appends to a file.
-// If the file does not exist, it will be created.
-// If the file exists, it will be appended.
-// If you want to truncate the file, use WriteFile instead.
-// It sends input to next handler.
-func AppendFile(path ...string) Handler {
-	return writeFileWithFlag(os.O_WRONLY|os.O_CREATE|os.O_APPEND, path...)
-}
-
-// writeFileWithFlag returns a Handler that writes to a file with flag.
-func writeFileWithFlag(flag int, path ...string) Handler {
-	if len(path) == 0 {
-		panic("no path specified")
-	}
+// UnmarshalEach returns a Handler that unmarshal the input record by record.
+// It uses the parser.StreamParser to decode each record into a new T,
+// and calls fn as soon as the record is read, so it works with endless streams.
+// It sends nil to next handler.
+func UnmarshalEach[T any](p parser.StreamParser, fn func(c *Context, v T) error) Handler {
 	return HandlerFunc(func(c *Context, in any) (any, error) {
 		r, err := utils.Reader(in)
 		if err != nil {
 			return nil, err
 		}
 
-		ff := make([]*os.File, 0, len(path))
-		ww := make([]io.Writer, 0, len(path))
-		for i := range path {
-			if filepath.IsLocal(path[i]) {
-				path[i] = filepath.Join(c.Workdir(), path[i])
-			}
-			f, err := os.OpenFile(path[i], flag, 0644)
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed to open file")
+		err = p.Stream(r, func(decode func(v any) error) error {
+			var v T
+			if err := decode(&v); err != nil {
+				return err
 			}
-			ff = append(ff, f)
-			ww = append(ww, f)
+			return fn(c, v)
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal")
 		}
 
-		// copy to buffer
-		var buf bytes.Buffer
-		ww = append(ww, &buf)
+		return nil, nil
+	})
+}
 
-		_, err = io.Copy(io.MultiWriter(ww...), r)
+// Marshal returns a Handler that marshal the input.
+func Marshal(m func(any) ([]byte, error), v ...any) Handler {
+	if len(v) > 1 {
+		panic("too many arguments")
+	}
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		if len(v) == 1 {
+			in = v[0]
+		}
+		b, err := m(in)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to write to file")
+			return nil, errors.Wrapf(err, "failed to marshal")
 		}
+		return b, nil
+	})
+}
 
-		for _, f := range ff {
-			_ = f.Close()
+// OpenFile returns a Handler that opens a file using Context.FS.
+// It sends the opened File to next handler.
+func OpenFile(path string) Handler {
+	return HandlerFunc(func(c *Context, _ any) (any, error) {
+		f, err := c.FS().OpenFile(c.path(path), os.O_RDONLY, 0)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open file")
 		}
 
-		return &buf, nil
+		out, err := c.Next(f)
+
+		_ = f.Close()
+
+		return out, err
 	})
 }
+
+// readAll reads all bytes from input.
+func readAll(in any) ([]byte, error) {
+	r, err := utils.Reader(in)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read all")
+	}
+	return b, nil
+}
@@ -3,10 +3,13 @@ package yevna_test
 import (
 	"bytes"
 	"context"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/tlipoca9/yevna"
 	"github.com/tlipoca9/yevna/parser"
@@ -66,6 +69,74 @@ var _ = Describe("Common Handlers", func() {
 		})
 	})
 
+	Context("Handler - Tee", func() {
+		It("streams the input to next handler", func(ctx context.Context) {
+			var head []byte
+			err := y.Run(
+				ctx,
+				yevna.Input("hello world"),
+				yevna.Tee(buf),
+				yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+					Expect(in).NotTo(BeAssignableToTypeOf(&bytes.Buffer{}))
+					head = make([]byte, 5)
+					_, err := in.(io.Reader).Read(head)
+					return nil, err
+				}),
+			)
+			Expect(err).To(BeNil())
+			Expect(string(head)).To(Equal("hello"))
+			// the rest is copied after next handler returns
+			Expect(buf.String()).To(Equal("hello world"))
+		})
+
+		It("drains the input after next handlers finish reading", func(ctx context.Context) {
+			var got string
+			err := y.Run(
+				ctx,
+				yevna.Input("a\nb\na\n"),
+				yevna.Tee(buf),
+				yevna.Grep("a", false),
+				yevna.ToStr(),
+				yevna.Output(&got),
+			)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal("a\na\n"))
+			Expect(buf.String()).To(Equal("a\nb\na\n"))
+
+			buf.Reset()
+			var wg sync.WaitGroup
+			err = y.Run(
+				ctx,
+				yevna.Input(strings.Repeat("line\n", 1000)),
+				yevna.Tee(buf),
+				yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, _ = io.Copy(io.Discard, in.(io.Reader))
+					}()
+					return nil, nil
+				}),
+			)
+			wg.Wait()
+			Expect(err).To(BeNil())
+			Expect(buf.Len()).To(Equal(5000))
+		})
+
+		It("buffers the input", func(ctx context.Context) {
+			var got bytes.Buffer
+			err := y.Run(
+				ctx,
+				yevna.Input("hello"),
+				yevna.Tee(buf).Buffered(true),
+				yevna.Output(&got),
+			)
+			Expect(err).To(BeNil())
+			Expect(buf.String()).To(Equal("hello"))
+			Expect(got.String()).To(Equal("hello"))
+		})
+	})
+
 	Context("Handler - Input", func() {
 		It("should success", func(ctx context.Context) {
 			err := y.Run(
@@ -110,6 +181,107 @@ var _ = Describe("Common Handlers", func() {
 		})
 	})
 
+	Context("Handler - UnmarshalAuto", func() {
+		It("chooses the parser by the file name", func(ctx context.Context) {
+			var got map[string]any
+			err := y.Run(
+				ctx,
+				yevna.OpenFile("tests/test.json"),
+				yevna.UnmarshalAuto(&got),
+			)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(map[string]any{
+				"name":  "Alice",
+				"value": 42.,
+			}))
+		})
+
+		It("chooses the parser by the Content-Type", func(ctx context.Context) {
+			var got map[string]any
+			err := y.Run(
+				ctx,
+				yevna.HTTP(func(c *yevna.Context, in any) (*http.Request, error) {
+					return http.NewRequest(http.MethodGet, svc.URL+"/ipinfo.yaml", nil)
+				}),
+				yevna.UnmarshalAuto(&got),
+			)
+			Expect(err).To(BeNil())
+			Expect(got).To(HaveKeyWithValue("hostname", "one.one.one.one"))
+		})
+
+		It("doesn't use the Content-Type of a previous response", func(ctx context.Context) {
+			var got map[string]any
+			err := y.Run(
+				ctx,
+				yevna.HTTP(func(c *yevna.Context, in any) (*http.Request, error) {
+					return http.NewRequest(http.MethodGet, svc.URL+"/ipinfo.yaml", nil)
+				}),
+				yevna.HandlerFunc(func(_ *yevna.Context, _ any) (any, error) {
+					return "[server]\nhost = \"localhost\"\n", nil
+				}),
+				yevna.UnmarshalAuto(&got),
+			)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(map[string]any{"server": map[string]any{"host": "localhost"}}))
+		})
+
+		It("detects the format from the content", func(ctx context.Context) {
+			var got map[string]any
+			err := y.Run(
+				ctx,
+				yevna.Input("[server]\nhost = \"localhost\"\nport = 8080\n"),
+				yevna.UnmarshalAuto(&got),
+			)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(map[string]any{
+				"server": map[string]any{"host": "localhost", "port": int64(8080)},
+			}))
+		})
+
+		It("fails if the format is unknown", func(ctx context.Context) {
+			var got map[string]any
+			err := y.Run(
+				ctx,
+				yevna.Input("hello world"),
+				yevna.UnmarshalAuto(&got),
+			)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Handler - UnmarshalEach", func() {
+		It("should decode records as they are written", func(ctx context.Context) {
+			type record struct {
+				Level string `logfmt:"level"`
+				Msg   string `logfmt:"msg"`
+			}
+			var got []record
+			seen := make(chan record, 1)
+			pr, pw := io.Pipe()
+			go func() {
+				defer GinkgoRecover()
+				defer pw.Close()
+				_, _ = io.WriteString(pw, "level=info msg=\"first\"\n")
+				// the first record is decoded while the writer is still open
+				Eventually(seen).Should(Receive(Equal(record{"info", "first"})))
+				_, _ = io.WriteString(pw, "level=warn msg=\"second\"\n")
+			}()
+			err := y.Run(
+				ctx,
+				yevna.Input(pr),
+				yevna.UnmarshalEach(parser.Logfmt(), func(_ *yevna.Context, r record) error {
+					got = append(got, r)
+					if len(got) == 1 {
+						seen <- r
+					}
+					return nil
+				}),
+			)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]record{{"info", "first"}, {"warn", "second"}}))
+		})
+	})
+
 	Context("Handler - OpenFile", func() {
 		It("should success", func(ctx context.Context) {
 			var got map[string]any
@@ -0,0 +1,137 @@
+package yevna
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	"github.com/cockroachdb/errors"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/tlipoca9/yevna/utils"
+)
+
+// Gzip returns a Handler that compresses the input with gzip at level,
+// e.g. gzip.DefaultCompression or gzip.BestCompression.
+// It streams the compressed data as io.Reader to next handler.
+func Gzip(level int) Handler {
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		// check the level before streaming
+		if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+			return nil, errors.Wrap(err, "failed to create gzip writer")
+		}
+		return compress(c, in, func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriterLevel(w, level)
+		})
+	})
+}
+
+// Gunzip returns a Handler that decompresses the gzip input.
+// It streams the decompressed data as io.Reader to next handler.
+func Gunzip() Handler {
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		r, err := utils.Reader(in)
+		if err != nil {
+			return nil, err
+		}
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read gzip")
+		}
+		return gr, nil
+	})
+}
+
+// Zstd returns a Handler that compresses the input with zstd at the default level.
+// It streams the compressed data as io.Reader to next handler.
+func Zstd() Handler {
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		return compress(c, in, func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		})
+	})
+}
+
+// Unzstd returns a Handler that decompresses the zstd input.
+// It streams the decompressed data as io.Reader to next handler.
+func Unzstd() Handler {
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		r, err := utils.Reader(in)
+		if err != nil {
+			return nil, err
+		}
+		return unzstd(r)
+	})
+}
+
+// Bzip2Decode returns a Handler that decompresses the bzip2 input.
+// It streams the decompressed data as io.Reader to next handler.
+func Bzip2Decode() Handler {
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		r, err := utils.Reader(in)
+		if err != nil {
+			return nil, err
+		}
+		return bzip2.NewReader(r), nil
+	})
+}
+
+// AutoDecompress returns a Handler that decompresses the input by sniffing its magic bytes.
+// It supports gzip, zstd and bzip2, and the input in other formats is passed through unchanged.
+// It streams the decompressed data as io.Reader to next handler.
+func AutoDecompress() Handler {
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		r, err := utils.Reader(in)
+		if err != nil {
+			return nil, err
+		}
+
+		br := bufio.NewReader(r)
+		magic, _ := br.Peek(4)
+		switch {
+		case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+			gr, err := gzip.NewReader(br)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read gzip")
+			}
+			return gr, nil
+		case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+			return unzstd(br)
+		case bytes.HasPrefix(magic, []byte("BZh")):
+			return bzip2.NewReader(br), nil
+		default:
+			return br, nil
+		}
+	})
+}
+
+// compress copies the input to the writer created by newWriter in a goroutine,
+// and sends the reader of the compressed data to next handler, see stream.
+func compress(
+	c *Context,
+	in any,
+	newWriter func(w io.Writer) (io.WriteCloser, error),
+) (any, error) {
+	return stream(c, in, func(r io.Reader, w io.Writer) error {
+		cw, err := newWriter(w)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(cw, r)
+		if cerr := cw.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	})
+}
+
+func unzstd(r io.Reader) (io.Reader, error) {
+	// the synchronous decoder starts no goroutines, so it needn't be closed
+	zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read zstd")
+	}
+	return zr, nil
+}
@@ -0,0 +1,127 @@
+package yevna_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/tlipoca9/yevna"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - Compress", func() {
+	y := yevna.New()
+	text := strings.Repeat("hello yevna\n", 1000)
+
+	run := func(ctx context.Context, in any, handlers ...yevna.Handler) (string, error) {
+		var got string
+		handlers = append([]yevna.Handler{yevna.Input(in)}, handlers...)
+		handlers = append(handlers, yevna.ToStr(), yevna.Output(&got))
+		err := y.Run(ctx, handlers...)
+		return got, err
+	}
+
+	It("stops compressing if the output isn't read", func(ctx context.Context) {
+		before := runtime.NumGoroutine()
+		for range 10 {
+			Expect(
+				y.Run(ctx, yevna.Input(io.LimitReader(zeros{}, 1<<20)), yevna.Zstd()),
+			).To(Succeed())
+		}
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", before))
+
+		ctx, cancel := context.WithCancel(ctx)
+		err := y.Run(
+			ctx,
+			yevna.Input(zeros{}),
+			yevna.Gzip(gzip.BestSpeed),
+			yevna.HandlerFunc(func(c *yevna.Context, in any) (any, error) {
+				cancel()
+				_, err := io.Copy(io.Discard, in.(io.Reader))
+				return nil, err
+			}),
+		)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("round trips gzip", func(ctx context.Context) {
+		var compressed bytes.Buffer
+		err := y.Run(
+			ctx,
+			yevna.Input(text),
+			yevna.Gzip(gzip.BestCompression),
+			yevna.Tee(&compressed),
+		)
+		Expect(err).To(BeNil())
+		Expect(compressed.Len()).To(BeNumerically("<", len(text)))
+
+		Expect(run(ctx, compressed.Bytes(), yevna.Gunzip())).To(Equal(text))
+		Expect(run(ctx, compressed.Bytes(), yevna.AutoDecompress())).To(Equal(text))
+	})
+
+	It("round trips zstd", func(ctx context.Context) {
+		Expect(run(ctx, text, yevna.Zstd(), yevna.Unzstd())).To(Equal(text))
+		Expect(run(ctx, text, yevna.Zstd(), yevna.AutoDecompress())).To(Equal(text))
+	})
+
+	It("decodes bzip2", func(ctx context.Context) {
+		b, err := base64.StdEncoding.DecodeString(
+			"QlpoOTFBWSZTWatrofEAAALZgAAQQAAQABJkwBAgADEA000EAB6j705RogeLuSKcKEhVtdD4gA==",
+		)
+		Expect(err).To(BeNil())
+		Expect(run(ctx, b, yevna.Bzip2Decode())).To(Equal("hello bzip2\n"))
+		Expect(run(ctx, b, yevna.AutoDecompress())).To(Equal("hello bzip2\n"))
+	})
+
+	It("passes through uncompressed input", func(ctx context.Context) {
+		Expect(run(ctx, "hi", yevna.AutoDecompress())).To(Equal("hi"))
+	})
+
+	It("streams without buffering", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(text),
+			yevna.Gzip(gzip.DefaultCompression),
+			yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+				Expect(in).To(BeAssignableToTypeOf(&io.PipeReader{}))
+				Expect(io.Copy(io.Discard, in.(io.Reader))).To(BeNumerically(">", 0))
+				return in, nil
+			}),
+		)
+		Expect(err).To(BeNil())
+	})
+
+	It("fails on invalid input", func(ctx context.Context) {
+		_, err := run(ctx, "not gzip", yevna.Gunzip())
+		Expect(err).To(HaveOccurred())
+		_, err = run(ctx, "x", yevna.Gzip(42))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("unpacks a tar.gz", func(ctx context.Context) {
+		dir := GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "tool"), []byte("tool"), 0755)).To(Succeed())
+
+		var got []string
+		err := y.Run(
+			ctx,
+			yevna.Chdir(dir),
+			yevna.Tar("tool"),
+			yevna.Gzip(gzip.DefaultCompression),
+			yevna.AutoDecompress(),
+			yevna.Untar("out"),
+			yevna.Output(&got),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal([]string{"out/tool"}))
+		Expect(os.ReadFile(filepath.Join(dir, "out", "tool"))).To(Equal([]byte("tool")))
+	})
+})
@@ -0,0 +1,466 @@
+package yevna
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cockroachdb/errors"
+	"github.com/fatih/color"
+	"github.com/goccy/go-json"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/lexer"
+	"github.com/goccy/go-yaml/printer"
+	"github.com/goccy/go-yaml/token"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// Format defines a data serialization format.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// Convert returns a Handler that transcodes the input from one format to another.
+// It decodes the input into a generic tree which preserves key order,
+// so keys are written in the same order as they appear in the input.
+// Null values are dropped when converting to TOML, since TOML has no null.
+// It sends the converted content to next handler.
+func Convert(from, to Format) Handler {
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		b, err := readAll(in)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := decodeOrdered(from, b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %s", from)
+		}
+
+		out, err := encodeOrdered(to, v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode %s", to)
+		}
+
+		return bytes.NewBuffer(out), nil
+	})
+}
+
+// PrettyJSON returns a Handler that indents the input json.
+// Each element begins on a new line indented with one or more copies of indent.
+func PrettyJSON(indent string) Handler {
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		b, err := readAll(in)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err = json.Indent(&buf, b, "", indent); err != nil {
+			return nil, errors.Wrap(err, "failed to indent json")
+		}
+		return &buf, nil
+	})
+}
+
+// CompactJSON returns a Handler that removes insignificant whitespaces from the input json.
+func CompactJSON() Handler {
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		b, err := readAll(in)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err = json.Compact(&buf, b); err != nil {
+			return nil, errors.Wrap(err, "failed to compact json")
+		}
+		return &buf, nil
+	})
+}
+
+// Colorize returns a Handler that highlights the input json or yaml with ANSI escape codes.
+// It is intended for printing to terminals.
+func Colorize() Handler {
+	property := func(attr color.Attribute) printer.PrintFunc {
+		return func() *printer.Property {
+			return &printer.Property{
+				Prefix: "\x1b[" + strconv.Itoa(int(attr)) + "m",
+				Suffix: "\x1b[" + strconv.Itoa(int(color.Reset)) + "m",
+			}
+		}
+	}
+	p := printer.Printer{
+		MapKey: property(color.FgHiCyan),
+		Anchor: property(color.FgHiYellow),
+		Alias:  property(color.FgHiYellow),
+		Bool:   property(color.FgHiMagenta),
+		String: property(color.FgHiGreen),
+		Number: property(color.FgHiMagenta),
+	}
+
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		b, err := readAll(in)
+		if err != nil {
+			return nil, err
+		}
+
+		// print the surrounding whitespaces of each token outside the escape codes
+		var buf bytes.Buffer
+		for _, tk := range lexer.Tokenize(string(b)) {
+			origin := strings.TrimLeftFunc(tk.Origin, unicode.IsSpace)
+			buf.WriteString(tk.Origin[:len(tk.Origin)-len(origin)])
+			trimmed := strings.TrimRightFunc(origin, unicode.IsSpace)
+			if trimmed != "" {
+				t := *tk
+				t.Origin = trimmed
+				buf.WriteString(p.PrintTokens(token.Tokens{&t}))
+			}
+			buf.WriteString(origin[len(trimmed):])
+		}
+		// the lexer drops the trailing whitespaces of the document
+		rest := b[len(bytes.TrimRightFunc(b, unicode.IsSpace)):]
+		if !bytes.HasSuffix(buf.Bytes(), rest) {
+			buf.Write(rest)
+		}
+		return &buf, nil
+	})
+}
+
+// decodeOrdered decodes b into a generic tree.
+// Objects are decoded as yaml.MapSlice, and arrays as []any.
+func decodeOrdered(f Format, b []byte) (any, error) {
+	switch f {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		return decodeOrderedJSON(dec)
+	case FormatYAML:
+		var v any
+		err := yaml.UnmarshalWithOptions(b, &v, yaml.UseOrderedMap())
+		return v, err
+	case FormatTOML:
+		var v map[string]any
+		if err := toml.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		order, err := tomlKeyOrder(b)
+		if err != nil {
+			return nil, err
+		}
+		return orderTOML(v, "", order), nil
+	default:
+		return nil, errors.Newf("unsupported format %q", f)
+	}
+}
+
+// encodeOrdered encodes the generic tree v.
+func encodeOrdered(f Format, v any) ([]byte, error) {
+	switch f {
+	case FormatJSON:
+		var buf bytes.Buffer
+		err := encodeOrderedJSON(&buf, v)
+		return buf.Bytes(), err
+	case FormatYAML:
+		return yaml.MarshalWithOptions(v, yaml.CustomMarshaler(func(n json.Number) ([]byte, error) {
+			return []byte(n), nil
+		}))
+	case FormatTOML:
+		if n, ok := findNumber(v); ok {
+			return nil, errors.Newf(
+				"integer %s overflows 64 bits, which can't be represented in toml",
+				n,
+			)
+		}
+		return toml.Marshal(toTOML(v))
+	default:
+		return nil, errors.Newf("unsupported format %q", f)
+	}
+}
+
+// decodeOrderedJSON decodes the next json value from dec.
+// Integers are decoded as int64 or uint64, or kept as json.Number if they overflow 64 bits.
+// Other numbers are decoded as float64.
+func decodeOrderedJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			ms := yaml.MapSlice{}
+			for dec.More() {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				v, err := decodeOrderedJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				ms = append(ms, yaml.MapItem{Key: k, Value: v})
+			}
+			_, err = dec.Token()
+			return ms, err
+		case '[':
+			arr := []any{}
+			for dec.More() {
+				v, err := decodeOrderedJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, v)
+			}
+			_, err = dec.Token()
+			return arr, err
+		default:
+			return nil, errors.Newf("unexpected delimiter %v", t)
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		if !strings.ContainsAny(t.String(), ".eE") {
+			return t, nil
+		}
+		return t.Float64()
+	default:
+		return t, nil
+	}
+}
+
+// encodeOrderedJSON writes the generic tree v as compact json.
+func encodeOrderedJSON(buf *bytes.Buffer, v any) error {
+	switch t := v.(type) {
+	case yaml.MapSlice:
+		buf.WriteByte('{')
+		for i, item := range t {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			k, err := json.Marshal(toString(item.Key))
+			if err != nil {
+				return err
+			}
+			buf.Write(k)
+			buf.WriteByte(':')
+			if err = encodeOrderedJSON(buf, item.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range t {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeOrderedJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	return nil
+}
+
+// findNumber returns the first json.Number in the generic tree v,
+// which is an integer overflowing 64 bits.
+func findNumber(v any) (json.Number, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		return t, true
+	case yaml.MapSlice:
+		for _, item := range t {
+			if n, ok := findNumber(item.Value); ok {
+				return n, true
+			}
+		}
+	case []any:
+		for _, item := range t {
+			if n, ok := findNumber(item); ok {
+				return n, true
+			}
+		}
+	}
+	return "", false
+}
+
+// toString converts a map key to string.
+func toString(k any) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	b, err := yaml.Marshal(k)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// tomlKeyOrder returns the position of each key path in the order of appearance.
+// The key path is joined with NUL, since keys may contain dots.
+func tomlKeyOrder(b []byte) (map[string]int, error) {
+	order := make(map[string]int)
+	add := func(path []string) {
+		for i := range path {
+			k := strings.Join(path[:i+1], "\x00")
+			if _, ok := order[k]; !ok {
+				order[k] = len(order)
+			}
+		}
+	}
+	keys := func(it unstable.Iterator) []string {
+		var ret []string
+		for it.Next() {
+			ret = append(ret, string(it.Node().Data))
+		}
+		return ret
+	}
+	var walk func(path []string, n *unstable.Node)
+	walk = func(path []string, n *unstable.Node) {
+		switch n.Kind {
+		case unstable.InlineTable:
+			for it := n.Children(); it.Next(); {
+				kv := it.Node()
+				p := append(slices.Clone(path), keys(kv.Key())...)
+				add(p)
+				walk(p, kv.Value())
+			}
+		case unstable.Array:
+			for it := n.Children(); it.Next(); {
+				walk(path, it.Node())
+			}
+		}
+	}
+
+	var (
+		p     unstable.Parser
+		table []string
+	)
+	p.Reset(b)
+	for p.NextExpression() {
+		e := p.Expression()
+		switch e.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = keys(e.Key())
+			add(table)
+		case unstable.KeyValue:
+			path := append(slices.Clone(table), keys(e.Key())...)
+			add(path)
+			walk(path, e.Value())
+		}
+	}
+	return order, p.Error()
+}
+
+// orderTOML converts maps in v to yaml.MapSlice using order.
+// Keys not found in order are placed at the end in lexical order.
+func orderTOML(v any, prefix string, order map[string]int) any {
+	switch t := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		pos := func(k string) int {
+			if i, ok := order[prefix+k]; ok {
+				return i
+			}
+			return len(order)
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			if c := pos(a) - pos(b); c != 0 {
+				return c
+			}
+			return strings.Compare(a, b)
+		})
+		ms := make(yaml.MapSlice, 0, len(keys))
+		for _, k := range keys {
+			ms = append(ms, yaml.MapItem{Key: k, Value: orderTOML(t[k], prefix+k+"\x00", order)})
+		}
+		return ms
+	case []any:
+		arr := make([]any, 0, len(t))
+		for _, item := range t {
+			arr = append(arr, orderTOML(item, prefix, order))
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+// toTOML converts yaml.MapSlice in v to structs, so that go-toml keeps the key order.
+// Null values are dropped.
+func toTOML(v any) any {
+	switch t := v.(type) {
+	case yaml.MapSlice:
+		fields := make([]reflect.StructField, 0, len(t))
+		values := make([]reflect.Value, 0, len(t))
+		for _, item := range t {
+			if item.Value == nil {
+				continue
+			}
+			k := toString(item.Key)
+			tag := reflect.StructTag(`toml:"` + k + `"`)
+			if k == "" || k == "-" || strings.Contains(k, ",") || tag.Get("toml") != k {
+				// the key can't be represented in a struct tag
+				return toTOMLMap(t)
+			}
+			value := reflect.ValueOf(toTOML(item.Value))
+			fields = append(fields, reflect.StructField{
+				Name: "F" + strconv.Itoa(len(fields)),
+				Type: value.Type(),
+				Tag:  tag,
+			})
+			values = append(values, value)
+		}
+		s := reflect.New(reflect.StructOf(fields)).Elem()
+		for i, value := range values {
+			s.Field(i).Set(value)
+		}
+		return s.Interface()
+	case []any:
+		arr := make([]any, 0, len(t))
+		for _, item := range t {
+			if item != nil {
+				arr = append(arr, toTOML(item))
+			}
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+// toTOMLMap converts ms to map[string]any, the key order is lost.
+func toTOMLMap(ms yaml.MapSlice) map[string]any {
+	m := make(map[string]any, len(ms))
+	for _, item := range ms {
+		if item.Value != nil {
+			m[toString(item.Key)] = toTOML(item.Value)
+		}
+	}
+	return m
+}
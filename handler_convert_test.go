@@ -0,0 +1,232 @@
+package yevna_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+
+	"github.com/tlipoca9/yevna"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - Convert", func() {
+	var buf *bytes.Buffer
+	y := yevna.New()
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+	})
+
+	It("should convert json to yaml preserving key order", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"name": "foo", "image": {"tag": "v1", "repository": "nginx"}, `+
+				`"ports": [80, 443], "debug": false, "ratio": 1.5}`),
+			yevna.Convert(yevna.FormatJSON, yevna.FormatYAML),
+			yevna.Tee(buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal(`
+name: foo
+image:
+  tag: v1
+  repository: nginx
+ports:
+- 80
+- 443
+debug: false
+ratio: 1.5
+`[1:]))
+	})
+
+	It("should convert yaml to json preserving key order", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input("zeta: 1\nalpha:\n  b: true\n  a: [x, y]\nnothing: null\n"),
+			yevna.Convert(yevna.FormatYAML, yevna.FormatJSON),
+			yevna.Tee(buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal(`{"zeta":1,"alpha":{"b":true,"a":["x","y"]},"nothing":null}`))
+	})
+
+	It("should convert toml to json preserving key order", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(`
+title = "example"
+owner = { name = "Tom", dob = "1979" }
+
+[servers.beta]
+ip = "10.0.0.2"
+
+[servers.alpha]
+ip = "10.0.0.1"
+
+[[products]]
+name = "Hammer"
+sku = 738594937
+`[1:]),
+			yevna.Convert(yevna.FormatTOML, yevna.FormatJSON),
+			yevna.Tee(buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal(
+			`{"title":"example","owner":{"name":"Tom","dob":"1979"},` +
+				`"servers":{"beta":{"ip":"10.0.0.2"},"alpha":{"ip":"10.0.0.1"}},` +
+				`"products":[{"name":"Hammer","sku":738594937}]}`,
+		))
+	})
+
+	It("should convert yaml to toml preserving key order", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input("title: example\nskip: null\nserver:\n  port: 8080\n  host: localhost\n"+
+				"items:\n- name: a\n- name: b\n"),
+			yevna.Convert(yevna.FormatYAML, yevna.FormatTOML),
+			yevna.Tee(buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal(`
+title = 'example'
+
+[server]
+port = 8080
+host = 'localhost'
+
+[[items]]
+name = 'a'
+
+[[items]]
+name = 'b'
+`[1:]))
+	})
+
+	It(
+		"should keep keys and integers which can't be represented as struct tags in toml",
+		func(ctx context.Context) {
+			convert := func(in string) (string, error) {
+				var got string
+				err := y.Run(
+					ctx,
+					yevna.Input(in),
+					yevna.Convert(yevna.FormatJSON, yevna.FormatTOML),
+					yevna.ToStr(),
+					yevna.Output(&got),
+				)
+				return got, err
+			}
+			for in, expected := range map[string]string{
+				`{"-":1,"b":2}`:             "- = 1\nb = 2\n",
+				`{"a\\b":1}`:                "'a\\b' = 1\n",
+				`{"":1}`:                    "'' = 1\n",
+				`{"n":9223372036854775807}`: "n = 9223372036854775807\n",
+			} {
+				Expect(convert(in)).To(Equal(expected), in)
+			}
+
+			_, err := convert(`{"n":12345678901234567890}`)
+			Expect(err).To(MatchError(ContainSubstring("greater than max int64")))
+			_, err = convert(`{"n":123456789012345678901}`)
+			Expect(err).To(MatchError(ContainSubstring("overflows 64 bits")))
+		},
+	)
+
+	It("should keep integers overflowing 64 bits", func(ctx context.Context) {
+		convert := func(to yevna.Format) (string, error) {
+			var got string
+			err := y.Run(
+				ctx,
+				yevna.Input(`{"n":123456789012345678901,"a":[-123456789012345678901]}`),
+				yevna.Convert(yevna.FormatJSON, to),
+				yevna.ToStr(),
+				yevna.Output(&got),
+			)
+			return got, err
+		}
+
+		got, err := convert(yevna.FormatJSON)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal(`{"n":123456789012345678901,"a":[-123456789012345678901]}`))
+
+		got, err = convert(yevna.FormatYAML)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("\"n\": 123456789012345678901\na:\n- -123456789012345678901\n"))
+	})
+
+	It("should fail on unsupported format", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(`{}`),
+			yevna.Convert(yevna.FormatJSON, "xml"),
+		)
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("Handler - PrettyJSON", func() {
+	y := yevna.New()
+
+	It("should indent json", func(ctx context.Context) {
+		var buf bytes.Buffer
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"b":1,"a":[true,null]}`),
+			yevna.PrettyJSON("  "),
+			yevna.Tee(&buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal("{\n  \"b\": 1,\n  \"a\": [\n    true,\n    null\n  ]\n}"))
+	})
+})
+
+var _ = Describe("Handler - CompactJSON", func() {
+	y := yevna.New()
+
+	It("should compact json", func(ctx context.Context) {
+		var buf bytes.Buffer
+		err := y.Run(
+			ctx,
+			yevna.Input("{\n  \"b\": 1,\n  \"a\": [\n    true,\n    null\n  ]\n}"),
+			yevna.CompactJSON(),
+			yevna.Tee(&buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal(`{"b":1,"a":[true,null]}`))
+	})
+})
+
+var _ = Describe("Handler - Colorize", func() {
+	y := yevna.New()
+
+	It("should highlight json", func(ctx context.Context) {
+		var buf bytes.Buffer
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"name": "foo", "value": 42}`),
+			yevna.Colorize(),
+			yevna.Tee(&buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(ContainSubstring("\x1b[96m\"name\"\x1b[0m"))
+		Expect(buf.String()).To(ContainSubstring("\x1b[92m\"foo\"\x1b[0m"))
+		Expect(buf.String()).To(ContainSubstring("\x1b[95m42\x1b[0m"))
+	})
+
+	It("should keep yaml content", func(ctx context.Context) {
+		var buf bytes.Buffer
+		input := "name: foo\nitems:\n  - a: 1\n    b: true\n# comment\nnested:\n  key: 'value'\n"
+		err := y.Run(
+			ctx,
+			yevna.Input(input),
+			yevna.Colorize(),
+			yevna.Tee(&buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(
+			regexp.MustCompile("\x1b\\[[0-9]+m").ReplaceAllString(buf.String(), ""),
+		).To(Equal(input))
+	})
+})
@@ -5,6 +5,8 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"sync/atomic"
+	"syscall"
 
 	"github.com/cockroachdb/errors"
 	"mvdan.cc/sh/v3/shell"
@@ -19,6 +21,8 @@ import (
 //   - stderr is sent to os.Stderr if silent is false.
 //
 // It starts the command and waits after the next handler is called.
+// If next handler closes stdout before reading all of it, e.g. Head,
+// the command killed by SIGPIPE isn't an error.
 func Exec(name string, args ...string) Handler {
 	return HandlerFunc(func(c *Context, in any) (any, error) {
 		var (
@@ -45,16 +49,42 @@ func Exec(name string, args ...string) Handler {
 		if err = cmd.Start(); err != nil {
 			return nil, errors.Wrapf(err, "failed to start command")
 		}
-		res, err := c.Next(stdout)
+		out := &cmdOutput{ReadCloser: stdout}
+		res, err := c.Next(out)
 		if err != nil {
 			_ = cmd.Cancel()
 			return nil, err
 		}
 
-		return res, cmd.Wait()
+		err = cmd.Wait()
+		if out.closed.Load() && brokenPipe(err) {
+			err = nil
+		}
+		return res, err
 	})
 }
 
+// cmdOutput is the stdout of a command, it records whether it is closed by next handler.
+type cmdOutput struct {
+	io.ReadCloser
+	closed atomic.Bool
+}
+
+func (o *cmdOutput) Close() error {
+	o.closed.Store(true)
+	return o.ReadCloser.Close()
+}
+
+// brokenPipe reports whether the command is killed by SIGPIPE.
+func brokenPipe(err error) bool {
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		return false
+	}
+	ws, ok := ee.Sys().(syscall.WaitStatus)
+	return ok && ws.Signaled() && ws.Signal() == syscall.SIGPIPE
+}
+
 // Execs returns a Handler that executes a command.
 // It uses shell.Fields to parse the command.
 // It is a shortcut for Exec(shell.Fields(cmd)).
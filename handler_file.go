@@ -0,0 +1,465 @@
+package yevna
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/tlipoca9/yevna/utils"
+)
+
+// WriteFileHandler is a Handler that writes the input to files using Context.FS.
+//
+// It copies the input to the files with constant memory,
+// completes the files, e.g. renamed for Atomic,
+// then sends a reader of the written content, which re-opens the first file, to next handler,
+// so next handlers see the complete files.
+// If the input fails midway, the files are left half-written unless Atomic is set.
+// If Buffered is set, it reads all input into memory,
+// and sends it as *bytes.Buffer to next handler.
+type WriteFileHandler struct {
+	paths         []string
+	append        bool
+	mode          fs.FileMode
+	uid, gid      int
+	atomic        bool
+	backup        bool
+	mkdir         bool
+	skipUnchanged bool
+	changed       *bool
+	buffered      bool
+}
+
+// WriteFile returns a Handler that writes to a file.
+// If the file does not exist, it will be created.
+// If the file exists, it will be truncated.
+// If you want to append to a file, use AppendFile instead.
+// It sends input to next handler.
+func WriteFile(path ...string) *WriteFileHandler {
+	if len(path) == 0 {
+		panic("no path specified")
+	}
+	return &WriteFileHandler{paths: path, uid: -1, gid: -1}
+}
+
+// AppendFile returns a Handler that appends to a file.
+// If the file does not exist, it will be created.
+// If the file exists, it will be appended.
+// If you want to truncate the file, use WriteFile instead.
+// Atomic and SkipUnchanged have no effect on AppendFile.
+// It sends input to next handler.
+func AppendFile(path ...string) *WriteFileHandler {
+	h := WriteFile(path...)
+	h.append = true
+	return h
+}
+
+// WithMode sets the permission bits of the file.
+// By default, new files are created with 0644 and existing files keep their permission bits.
+func (h *WriteFileHandler) WithMode(mode fs.FileMode) *WriteFileHandler {
+	h.mode = mode
+	return h
+}
+
+// WithOwner sets the owner of the file, -1 keeps the uid or gid unchanged.
+func (h *WriteFileHandler) WithOwner(uid, gid int) *WriteFileHandler {
+	h.uid, h.gid = uid, gid
+	return h
+}
+
+// Atomic sets the atomic mode.
+// If atomic is true, it writes to a temporary file in the same directory, syncs it,
+// and renames it to the file, so the file is never left half-written.
+func (h *WriteFileHandler) Atomic(atomic bool) *WriteFileHandler {
+	h.atomic = atomic
+	return h
+}
+
+// Backup sets whether to copy the existing file to "<path>.bak" before changing it.
+func (h *WriteFileHandler) Backup(backup bool) *WriteFileHandler {
+	h.backup = backup
+	return h
+}
+
+// MkdirAll sets whether to create the missing parent directories.
+func (h *WriteFileHandler) MkdirAll(mkdir bool) *WriteFileHandler {
+	h.mkdir = mkdir
+	return h
+}
+
+// SkipUnchanged sets whether to leave the file untouched if its content equals the input.
+func (h *WriteFileHandler) SkipUnchanged(skip bool) *WriteFileHandler {
+	h.skipUnchanged = skip
+	return h
+}
+
+// Changed reports whether any file is created or its content is changed into changed.
+// It is set before next handler runs.
+func (h *WriteFileHandler) Changed(changed *bool) *WriteFileHandler {
+	h.changed = changed
+	return h
+}
+
+// Buffered sets the buffered mode, for next handlers which need to read the input more than once.
+func (h *WriteFileHandler) Buffered(buffered bool) *WriteFileHandler {
+	h.buffered = buffered
+	return h
+}
+
+// Handle implements Handler.
+func (h *WriteFileHandler) Handle(c *Context, in any) (any, error) {
+	var (
+		r   io.Reader
+		b   []byte
+		err error
+	)
+	if h.buffered {
+		b, err = readAll(in)
+		r = bytes.NewReader(b)
+	} else {
+		r, err = utils.Reader(in)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fsys := c.FS()
+	files := make([]*fileWriter, 0, len(h.paths))
+	writers := make([]io.Writer, 0, len(h.paths))
+	abort := func() {
+		for _, f := range files {
+			f.abort()
+		}
+	}
+	for _, path := range h.paths {
+		path = c.path(path)
+		f, err := h.open(fsys, path)
+		if err != nil {
+			abort()
+			return nil, errors.Wrapf(err, "failed to write file %s", path)
+		}
+		files = append(files, f)
+		writers = append(writers, f)
+	}
+
+	if _, err = io.Copy(io.MultiWriter(writers...), r); err != nil {
+		abort()
+		return nil, errors.Wrap(err, "failed to copy")
+	}
+
+	changed := false
+	for i, f := range files {
+		ok, err := f.commit()
+		if err != nil {
+			for _, f := range files[i+1:] {
+				f.abort()
+			}
+			return nil, errors.Wrapf(err, "failed to write file %s", f.path)
+		}
+		changed = changed || ok
+	}
+	if h.changed != nil {
+		*h.changed = changed
+	}
+
+	if h.buffered {
+		return bytes.NewBuffer(b), nil
+	}
+	first := files[0]
+	var offset int64
+	if h.append {
+		info, err := fsys.Stat(first.path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to stat file %s", first.path)
+		}
+		offset = info.Size() - first.n
+	}
+	return &fileReader{fsys: fsys, path: first.path, offset: offset, n: first.n}, nil
+}
+
+// fileReader reads the n bytes written to a file from offset,
+// so the content appended later, e.g. by AppendFile to the same file, isn't read.
+// The file is opened on the first read, and closed at the end.
+type fileReader struct {
+	fsys   FS
+	path   string
+	offset int64
+	n      int64
+	f      io.ReadCloser
+	err    error
+}
+
+// Name returns the path of the file, e.g. for UnmarshalAuto.
+func (r *fileReader) Name() string {
+	return r.path
+}
+
+func (r *fileReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.f == nil {
+		if r.err = r.open(); r.err != nil {
+			return 0, r.err
+		}
+	}
+	n, err := r.f.Read(p)
+	if err != nil {
+		r.err = err
+		_ = r.f.Close()
+	}
+	return n, err
+}
+
+func (r *fileReader) open() error {
+	f, err := r.fsys.Open(r.path)
+	if err != nil {
+		return err
+	}
+	if s, ok := f.(io.Seeker); ok {
+		_, err = s.Seek(r.offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(io.Discard, f, r.offset)
+	}
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.f = struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, r.n), f}
+	return nil
+}
+
+// Close closes the file, e.g. if next handler stops reading.
+func (r *fileReader) Close() error {
+	if r.err == nil {
+		r.err = fs.ErrClosed
+		if r.f != nil {
+			return r.f.Close()
+		}
+	}
+	return nil
+}
+
+// fileWriter writes the content of a file.
+// The content is staged in a temporary file in the same directory
+// if it is compared with the old content or written atomically.
+type fileWriter struct {
+	h    *WriteFileHandler
+	fsys FS
+	path string
+	info fs.FileInfo
+	perm fs.FileMode
+	f    File
+	tmp  string
+	n    int64
+}
+
+// open opens the file, or the temporary file, for writing.
+func (h *WriteFileHandler) open(fsys FS, path string) (*fileWriter, error) {
+	if h.mkdir {
+		if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := fsys.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	perm := h.mode
+	if perm == 0 {
+		perm = 0644
+		if info != nil {
+			perm = info.Mode().Perm()
+		}
+	}
+	w := &fileWriter{h: h, fsys: fsys, path: path, info: info, perm: perm}
+
+	if !h.append && (h.atomic || h.backup || h.skipUnchanged || h.changed != nil) {
+		if w.f, w.tmp, err = createTemp(fsys, path); err != nil {
+			return nil, err
+		}
+		if err = fsys.Chmod(w.tmp, perm); err == nil {
+			err = h.chmod(fsys, w.tmp, perm)
+		}
+		if err != nil {
+			w.abort()
+			return nil, err
+		}
+		return w, nil
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if h.append {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	if w.f, err = fsys.OpenFile(path, flag, perm); err != nil {
+		return nil, err
+	}
+	// change the mode and owner before writing, so that the content is never exposed
+	if err = h.chmod(fsys, path, perm); err != nil {
+		_ = w.f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// abort closes the file and removes the temporary file.
+func (w *fileWriter) abort() {
+	_ = w.f.Close()
+	if w.tmp != "" {
+		_ = w.fsys.Remove(w.tmp)
+	}
+}
+
+// commit completes the file, and reports whether the file is changed.
+func (w *fileWriter) commit() (changed bool, err error) {
+	if w.tmp == "" {
+		return w.info == nil || !w.h.append || w.n > 0, w.f.Close()
+	}
+	defer func() {
+		if err != nil {
+			_ = w.fsys.Remove(w.tmp)
+		}
+	}()
+
+	if s, ok := w.f.(interface{ Sync() error }); ok && w.h.atomic {
+		if err = s.Sync(); err != nil {
+			_ = w.f.Close()
+			return false, err
+		}
+	}
+	if err = w.f.Close(); err != nil {
+		return false, err
+	}
+
+	changed = true
+	if w.info != nil && w.info.Mode().IsRegular() {
+		same, err := equalFiles(w.fsys, w.tmp, w.path)
+		if err != nil {
+			return false, err
+		}
+		changed = !same
+	}
+	if !changed && w.h.skipUnchanged {
+		_ = w.fsys.Remove(w.tmp)
+		return false, w.h.chmod(w.fsys, w.path, w.perm)
+	}
+
+	if w.h.backup && w.info != nil && changed {
+		if err = copyFileTo(w.fsys, w.path, w.path+".bak", w.info.Mode().Perm()); err != nil {
+			return false, errors.Wrap(err, "failed to backup")
+		}
+	}
+
+	if w.h.atomic {
+		if err = w.fsys.Rename(w.tmp, w.path); err != nil {
+			return false, err
+		}
+		// sync the directory to persist the rename, it is not supported on all platforms
+		if d, derr := w.fsys.Open(filepath.Dir(w.path)); derr == nil {
+			if s, ok := d.(interface{ Sync() error }); ok {
+				_ = s.Sync()
+			}
+			_ = d.Close()
+		}
+		return changed, nil
+	}
+
+	// copy instead of rename to keep the file itself, e.g. its owner and hard links
+	if err = copyFileTo(w.fsys, w.tmp, w.path, w.perm); err != nil {
+		return false, err
+	}
+	if err = w.h.chmod(w.fsys, w.path, w.perm); err != nil {
+		return false, err
+	}
+	return changed, w.fsys.Remove(w.tmp)
+}
+
+// createTemp creates a new temporary file in the directory of path.
+func createTemp(fsys FS, path string) (File, string, error) {
+	dir, name := filepath.Split(path)
+	for i := 0; ; i++ {
+		tmp := filepath.Join(dir, "."+name+".tmp-"+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := fsys.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil || !errors.Is(err, fs.ErrExist) || i == 10 {
+			return f, tmp, err
+		}
+	}
+}
+
+// copyFileTo copies the content of the file src to dst.
+func copyFileTo(fsys FS, src, dst string, perm fs.FileMode) error {
+	f, err := fsys.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	err = copyFile(fsys, src, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// equalFiles reports whether the files have the same content.
+func equalFiles(fsys FS, a, b string) (bool, error) {
+	fa, err := fsys.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := fsys.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	ba, bb := make([]byte, 32*1024), make([]byte, 32*1024)
+	for {
+		na, erra := io.ReadFull(fa, ba)
+		nb, errb := io.ReadFull(fb, bb)
+		if !bytes.Equal(ba[:na], bb[:nb]) {
+			return false, nil
+		}
+		eofa := errors.Is(erra, io.EOF) || errors.Is(erra, io.ErrUnexpectedEOF)
+		eofb := errors.Is(errb, io.EOF) || errors.Is(errb, io.ErrUnexpectedEOF)
+		switch {
+		case erra != nil && !eofa:
+			return false, erra
+		case errb != nil && !eofb:
+			return false, errb
+		case eofa || eofb:
+			return eofa && eofb, nil
+		}
+	}
+}
+
+// chmod sets the mode and owner of the file if they are set explicitly.
+func (h *WriteFileHandler) chmod(fsys FS, path string, perm fs.FileMode) error {
+	if h.mode != 0 {
+		if err := fsys.Chmod(path, perm); err != nil {
+			return err
+		}
+	}
+	if h.uid >= 0 || h.gid >= 0 {
+		if err := fsys.Chown(path, h.uid, h.gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package yevna_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/iotest"
+	"time"
+
+	"github.com/tlipoca9/yevna"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - WriteFile", func() {
+	var (
+		y   = yevna.New()
+		dir string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	mode := func(path string) fs.FileMode {
+		info, err := os.Stat(path)
+		Expect(err).To(BeNil())
+		return info.Mode().Perm()
+	}
+
+	It("writes with the mode", func(ctx context.Context) {
+		path := filepath.Join(dir, "secret.env")
+		err := y.Run(ctx, yevna.Input("TOKEN=x"), yevna.WriteFile(path).WithMode(0600))
+		Expect(err).To(BeNil())
+		Expect(mode(path)).To(Equal(fs.FileMode(0600)))
+
+		// existing files keep their mode
+		err = y.Run(ctx, yevna.Input("TOKEN=y"), yevna.WriteFile(path).Atomic(true))
+		Expect(err).To(BeNil())
+		Expect(mode(path)).To(Equal(fs.FileMode(0600)))
+		Expect(os.ReadFile(path)).To(Equal([]byte("TOKEN=y")))
+	})
+
+	It("replaces the file atomically", func(ctx context.Context) {
+		path := filepath.Join(dir, "config.yaml")
+		Expect(os.WriteFile(path, []byte("a: 1\n"), 0640)).To(Succeed())
+
+		err := y.Run(ctx, yevna.Input("a: 2\n"), yevna.WriteFile(path).Atomic(true))
+		Expect(err).To(BeNil())
+		Expect(os.ReadFile(path)).To(Equal([]byte("a: 2\n")))
+		Expect(mode(path)).To(Equal(fs.FileMode(0640)))
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).To(BeNil())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("keeps the file if the input fails", func(ctx context.Context) {
+		path := filepath.Join(dir, "config.yaml")
+		Expect(os.WriteFile(path, []byte("a: 1\n"), 0644)).To(Succeed())
+
+		err := y.Run(ctx, yevna.Input(42), yevna.WriteFile(path).Atomic(true))
+		Expect(err).To(HaveOccurred())
+		Expect(os.ReadFile(path)).To(Equal([]byte("a: 1\n")))
+	})
+
+	It("backs up the file", func(ctx context.Context) {
+		path := filepath.Join(dir, "nginx.conf")
+		Expect(os.WriteFile(path, []byte("old"), 0644)).To(Succeed())
+
+		err := y.Run(ctx, yevna.Input("new"), yevna.WriteFile(path).Backup(true))
+		Expect(err).To(BeNil())
+		Expect(os.ReadFile(path)).To(Equal([]byte("new")))
+		Expect(os.ReadFile(path + ".bak")).To(Equal([]byte("old")))
+	})
+
+	It("creates the parent directories", func(ctx context.Context) {
+		path := filepath.Join(dir, "a", "b", "c.txt")
+		err := y.Run(ctx, yevna.Input("c"), yevna.WriteFile(path))
+		Expect(err).To(HaveOccurred())
+
+		err = y.Run(ctx, yevna.Input("c"), yevna.WriteFile(path).MkdirAll(true))
+		Expect(err).To(BeNil())
+		Expect(os.ReadFile(path)).To(Equal([]byte("c")))
+	})
+
+	It("skips the unchanged file and reports whether it is changed", func(ctx context.Context) {
+		path := filepath.Join(dir, "config.yaml")
+		var changed bool
+
+		err := y.Run(
+			ctx,
+			yevna.Input("a: 1\n"),
+			yevna.WriteFile(path).SkipUnchanged(true).Changed(&changed),
+		)
+		Expect(err).To(BeNil())
+		Expect(changed).To(BeTrue())
+
+		past := mustModTime(path).Add(-time.Hour)
+		Expect(os.Chtimes(path, past, past)).To(Succeed())
+		err = y.Run(
+			ctx,
+			yevna.Input("a: 1\n"),
+			yevna.WriteFile(path).SkipUnchanged(true).Backup(true).Changed(&changed),
+		)
+		Expect(err).To(BeNil())
+		Expect(changed).To(BeFalse())
+		Expect(mustModTime(path)).To(Equal(past))
+		Expect(path + ".bak").NotTo(BeAnExistingFile())
+
+		err = y.Run(
+			ctx,
+			yevna.Input("a: 2\n"),
+			yevna.WriteFile(path).SkipUnchanged(true).Changed(&changed),
+		)
+		Expect(err).To(BeNil())
+		Expect(changed).To(BeTrue())
+	})
+
+	It("appends to the file", func(ctx context.Context) {
+		path := filepath.Join(dir, "log.txt")
+		for _, line := range []string{"a\n", "b\n"} {
+			err := y.Run(ctx, yevna.Input(line), yevna.AppendFile(path))
+			Expect(err).To(BeNil())
+		}
+		Expect(os.ReadFile(path)).To(Equal([]byte("a\nb\n")))
+	})
+
+	It("writes the file before next handler runs", func(ctx context.Context) {
+		path := filepath.Join(dir, "hello.txt")
+		handlers := []*yevna.WriteFileHandler{
+			yevna.WriteFile(path),
+			yevna.WriteFile(path).Atomic(true),
+		}
+		for _, h := range handlers {
+			var got string
+			err := y.Run(
+				ctx,
+				yevna.Input("hello world\n"),
+				h,
+				yevna.Exec("cat", path),
+				yevna.ToStr(),
+				yevna.Output(&got),
+			)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal("hello world\n"))
+		}
+
+		var got string
+		err := y.Run(
+			ctx,
+			yevna.Input("again\n"),
+			yevna.AppendFile(path),
+			yevna.ToStr(),
+			yevna.Output(&got),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("again\n"))
+		Expect(os.ReadFile(path)).To(Equal([]byte("hello world\nagain\n")))
+	})
+
+	It("sends the written file to next handler with constant memory", func(ctx context.Context) {
+		path := filepath.Join(dir, "backup.img")
+		const size = 8 << 20
+		var first []byte
+		err := y.Run(
+			ctx,
+			yevna.Input(io.LimitReader(zeros{}, size)),
+			yevna.WriteFile(path),
+			yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+				Expect(in).NotTo(BeAssignableToTypeOf(&bytes.Buffer{}))
+				Expect(in.(interface{ Name() string }).Name()).To(Equal(path))
+				first = make([]byte, 1024)
+				_, err := io.ReadFull(in.(io.Reader), first)
+				return nil, err
+			}),
+		)
+		Expect(err).To(BeNil())
+		Expect(first).To(Equal(make([]byte, 1024)))
+		info, err := os.Stat(path)
+		Expect(err).To(BeNil())
+		Expect(info.Size()).To(Equal(int64(size)))
+	})
+
+	It("keeps the file if the input fails", func(ctx context.Context) {
+		path := filepath.Join(dir, "config.yaml")
+		Expect(os.WriteFile(path, []byte("a: 1\n"), 0644)).To(Succeed())
+
+		err := y.Run(
+			ctx,
+			yevna.Input(
+				io.MultiReader(
+					strings.NewReader("a: 2\n"),
+					iotest.ErrReader(errors.New("connection reset")),
+				),
+			),
+			yevna.WriteFile(path).Atomic(true),
+		)
+		Expect(err).To(MatchError(ContainSubstring("connection reset")))
+		Expect(os.ReadFile(path)).To(Equal([]byte("a: 1\n")))
+		entries, err := os.ReadDir(dir)
+		Expect(err).To(BeNil())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("buffers the input", func(ctx context.Context) {
+		path := filepath.Join(dir, "config.yaml")
+		var buf bytes.Buffer
+		err := y.Run(
+			ctx,
+			yevna.Input("a: 1\n"),
+			yevna.WriteFile(path).Buffered(true),
+			yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+				// the file is complete before next handler runs
+				Expect(os.ReadFile(path)).To(Equal([]byte("a: 1\n")))
+				return in, nil
+			}),
+			yevna.Output(&buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal("a: 1\n"))
+	})
+})
+
+// zeros is an endless reader of zero bytes.
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func mustModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	Expect(err).To(BeNil())
+	return info.ModTime()
+}
@@ -2,39 +2,240 @@ package yevna
 
 import (
 	"bytes"
-	"io"
+	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
 	"github.com/tidwall/gjson"
-
-	"github.com/tlipoca9/yevna/utils"
 )
 
 // Gjson returns a Handler that extracts the value using the path.
 func Gjson(path string) Handler {
 	return HandlerFunc(func(_ *Context, in any) (any, error) {
-		r, err := utils.Reader(in)
+		b, err := readJSON(in)
 		if err != nil {
 			return nil, err
 		}
 
-		b, err := io.ReadAll(r)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to read all")
-		}
-
-		if !gjson.ValidBytes(b) {
-			return nil, errors.New("invalid json")
-		}
-
 		var buf bytes.Buffer
 
 		value := gjson.GetBytes(b, path)
 		if !value.Exists() {
-			return nil, errors.New("path not found")
+			return nil, errors.Newf("path not found: %s", path)
 		}
 		buf.WriteString(value.Raw)
 
 		return &buf, nil
 	})
 }
+
+// GjsonMap returns a Handler that extracts multiple values in one pass.
+// The keys of paths are the keys of the output map,
+// and the values of paths are the gjson paths.
+// It returns an error listing every missing path.
+// It sends map[string]any to next handler.
+func GjsonMap(paths map[string]string) Handler {
+	keys := make([]string, 0, len(paths))
+	for k := range paths {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	pp := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pp = append(pp, paths[k])
+	}
+
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		b, err := readJSON(in)
+		if err != nil {
+			return nil, err
+		}
+
+		var missing []string
+		out := make(map[string]any, len(keys))
+		for i, value := range gjson.GetManyBytes(b, pp...) {
+			if !value.Exists() {
+				missing = append(missing, pp[i])
+				continue
+			}
+			out[keys[i]] = value.Value()
+		}
+		if len(missing) != 0 {
+			return nil, errors.Newf("paths not found: %s", strings.Join(missing, ", "))
+		}
+
+		return out, nil
+	})
+}
+
+// GjsonInto returns a Handler that extracts multiple values into a struct in one pass.
+// The fields of T are tagged with `gjson:"path"`, and the following options are supported:
+//   - optional: the field is left unchanged if the path is missing.
+//   - default=value: the value is used if the path is missing.
+//     It is assigned directly to string fields, otherwise it is decoded as json.
+//
+// It returns an error listing every missing path which is neither optional nor defaulted,
+// v is only modified if every path is resolved, and the nil embedded struct pointers are allocated.
+// It sends v to next handler.
+func GjsonInto[T any](v *T) Handler {
+	fields, err := gjsonFields(reflect.TypeOf(v).Elem())
+	if err != nil {
+		panic(err)
+	}
+	pp := make([]string, 0, len(fields))
+	for _, f := range fields {
+		pp = append(pp, f.path)
+	}
+
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		b, err := readJSON(in)
+		if err != nil {
+			return nil, err
+		}
+
+		// decode into a copy, so that v is only modified on success
+		var missing []string
+		rv := reflect.New(reflect.TypeOf(v).Elem()).Elem()
+		rv.Set(reflect.ValueOf(v).Elem())
+		for i, value := range gjson.GetManyBytes(b, pp...) {
+			f := fields[i]
+			if !value.Exists() && !f.hasDefault {
+				if !f.optional {
+					missing = append(missing, f.path)
+				}
+				continue
+			}
+
+			// the old value may share maps or pointers with v
+			fv, err := fieldByIndex(rv, f.index)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to set field %s", f.name)
+			}
+			fv.SetZero()
+			switch {
+			case value.Exists():
+				err = json.Unmarshal([]byte(value.Raw), fv.Addr().Interface())
+				if err != nil {
+					return nil, errors.Wrapf(
+						err,
+						"failed to decode path %s into field %s",
+						f.path,
+						f.name,
+					)
+				}
+			case f.hasDefault:
+				if fv.Kind() == reflect.String {
+					fv.SetString(f.def)
+					continue
+				}
+				err = json.Unmarshal([]byte(f.def), fv.Addr().Interface())
+				if err != nil {
+					return nil, errors.Wrapf(
+						err,
+						"failed to decode default value of field %s",
+						f.name,
+					)
+				}
+			}
+		}
+		if len(missing) != 0 {
+			return nil, errors.Newf("paths not found: %s", strings.Join(missing, ", "))
+		}
+
+		reflect.ValueOf(v).Elem().Set(rv)
+		return v, nil
+	})
+}
+
+// fieldByIndex returns the nested field of struct value rv.
+// The embedded struct pointers on the path are copied, or allocated if nil,
+// so that the field isn't shared.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			switch {
+			case rv.CanSet():
+				p := reflect.New(rv.Type().Elem())
+				if !rv.IsNil() {
+					p.Elem().Set(rv.Elem())
+				}
+				rv.Set(p)
+			case rv.IsNil():
+				return reflect.Value{}, errors.Newf(
+					"cannot set embedded pointer to unexported struct %s",
+					rv.Type().Elem(),
+				)
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, nil
+}
+
+// gjsonField describes a struct field tagged with `gjson:"path"`.
+type gjsonField struct {
+	name       string
+	index      []int
+	path       string
+	optional   bool
+	hasDefault bool
+	def        string
+}
+
+// gjsonFields returns the tagged fields of struct type t.
+func gjsonFields(t reflect.Type) ([]gjsonField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, errors.Newf("expected struct, got %s", t)
+	}
+
+	var fields []gjsonField
+	for _, sf := range reflect.VisibleFields(t) {
+		tag, ok := sf.Tag.Lookup("gjson")
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		path, opts, _ := strings.Cut(tag, ",")
+		if path == "" {
+			return nil, errors.Newf("empty gjson path of field %s", sf.Name)
+		}
+		f := gjsonField{name: sf.Name, index: sf.Index, path: path}
+		if opts != "" {
+			// default must be the last option, since its value may contain commas
+			for opts != "" {
+				var opt string
+				if strings.HasPrefix(opts, "default=") {
+					opt, opts = opts, ""
+				} else {
+					opt, opts, _ = strings.Cut(opts, ",")
+				}
+				switch {
+				case opt == "optional":
+					f.optional = true
+				case strings.HasPrefix(opt, "default="):
+					f.hasDefault = true
+					f.def = strings.TrimPrefix(opt, "default=")
+				default:
+					return nil, errors.Newf("unknown gjson option %q of field %s", opt, sf.Name)
+				}
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// readJSON reads all bytes from input and checks that they are valid json.
+func readJSON(in any) ([]byte, error) {
+	b, err := readAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if !gjson.ValidBytes(b) {
+		return nil, errors.New("invalid json")
+	}
+	return b, nil
+}
@@ -25,3 +25,111 @@ var _ = Describe("Handler - HTTP", func() {
 		Expect(got).To(Equal([]string{"Alice", "Bob"}))
 	})
 })
+
+var _ = Describe("Handler - GjsonMap", func() {
+	y := yevna.New()
+
+	It("should extract every path", func(ctx context.Context) {
+		var got map[string]any
+		err := y.Run(
+			ctx,
+			yevna.Input(
+				`{"metadata": {"name": "foo", "labels": {"app": "bar"}}, "spec": {"replicas": 2}}`,
+			),
+			yevna.GjsonMap(map[string]string{
+				"name":     "metadata.name",
+				"app":      "metadata.labels.app",
+				"replicas": "spec.replicas",
+			}),
+			yevna.Output(&got),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal(map[string]any{"name": "foo", "app": "bar", "replicas": 2.}))
+	})
+
+	It("should list every missing path", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"metadata": {"name": "foo"}}`),
+			yevna.GjsonMap(map[string]string{
+				"name": "metadata.name",
+				"app":  "metadata.labels.app",
+				"ns":   "metadata.namespace",
+			}),
+		)
+		Expect(err).To(MatchError("paths not found: metadata.labels.app, metadata.namespace"))
+	})
+})
+
+var _ = Describe("Handler - GjsonInto", func() {
+	y := yevna.New()
+
+	type pod struct {
+		Name      string            `gjson:"metadata.name"`
+		Namespace string            `gjson:"metadata.namespace,default=default"`
+		Labels    map[string]string `gjson:"metadata.labels,optional"`
+		Restarts  int               `gjson:"status.restarts,default=0"`
+		Phase     string            `gjson:"status.phase"`
+		Ignored   string
+	}
+
+	It("should extract into struct", func(ctx context.Context) {
+		var got pod
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"metadata": {"name": "foo", "labels": {"app": "bar"}}, `+
+				`"status": {"phase": "Running", "restarts": 3}}`),
+			yevna.GjsonInto(&got),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal(pod{
+			Name:      "foo",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "bar"},
+			Restarts:  3,
+			Phase:     "Running",
+		}))
+	})
+
+	It("should list every missing path", func(ctx context.Context) {
+		var got pod
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"status": {}}`),
+			yevna.GjsonInto(&got),
+		)
+		Expect(err).To(MatchError("paths not found: metadata.name, status.phase"))
+	})
+
+	It("should leave the struct unchanged on failure", func(ctx context.Context) {
+		got := pod{Name: "old", Labels: map[string]string{"app": "old"}, Ignored: "kept"}
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"metadata": {"name": "foo", "labels": {"app": "bar"}}, `+
+				`"status": {"restarts": "3"}}`),
+			yevna.GjsonInto(&got),
+		)
+		Expect(err).To(HaveOccurred())
+		Expect(got).To(Equal(
+			pod{Name: "old", Labels: map[string]string{"app": "old"}, Ignored: "kept"},
+		))
+	})
+
+	It("should allocate nil embedded pointers", func(ctx context.Context) {
+		type Metadata struct {
+			Name string `gjson:"metadata.name"`
+		}
+		type object struct {
+			*Metadata
+			Phase string `gjson:"status.phase"`
+		}
+		var got object
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"metadata": {"name": "foo"}, "status": {"phase": "Running"}}`),
+			yevna.GjsonInto(&got),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal(object{Metadata: &Metadata{Name: "foo"}, Phase: "Running"}))
+	})
+})
@@ -0,0 +1,268 @@
+package yevna
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/tlipoca9/yevna/utils"
+)
+
+// HashKey is the Context value key of the hex digest computed by Hash.
+const HashKey = "hash.digest"
+
+// ErrChecksumMismatch is returned by VerifyChecksum if the digest doesn't match.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// HashHandler is a Handler that computes the digest of the input
+// while passing it through unchanged.
+// The supported algorithms are sha256, sha512, md5 and blake2b (BLAKE2b-512).
+//
+// It sends the input as io.Reader to next handler,
+// and sets the hex digest as the Context value of HashKey once the stream is fully consumed.
+// The rest of the stream is drained after next handler returns.
+type HashHandler struct {
+	algo string
+	out  *string
+}
+
+// Hash returns a new HashHandler which computes the digest with algo.
+func Hash(algo string) *HashHandler {
+	if _, err := newHash(algo); err != nil {
+		panic(err)
+	}
+	return &HashHandler{algo: algo}
+}
+
+// Digest sets the pointer which receives the hex digest after next handler returns.
+func (h *HashHandler) Digest(out *string) *HashHandler {
+	h.out = out
+	return h
+}
+
+// Handle implements Handler.
+func (h *HashHandler) Handle(c *Context, in any) (any, error) {
+	r, err := utils.Reader(in)
+	if err != nil {
+		return nil, err
+	}
+
+	// onEOF may be called in the goroutine of a streaming handler, e.g. Grep,
+	// the values of Context are guarded
+	hr, _ := newHashReader(r, h.algo, func(sum string) error {
+		c.Value(HashKey, sum)
+		return nil
+	})
+	out, err := hr.next(c)
+	if err != nil {
+		return nil, err
+	}
+	if h.out != nil {
+		*h.out = hr.sum
+	}
+	return out, nil
+}
+
+// ChecksumHandler is a Handler that verifies the digest of the input
+// while passing it through unchanged.
+//
+// It sends the input as io.Reader to next handler.
+// The read at the end of the stream fails with ErrChecksumMismatch on mismatch,
+// so the streaming handlers like WriteFile(...).Atomic(true) and Untar fail before they complete.
+// The rest of the stream is drained and verified after next handler returns.
+type ChecksumHandler struct {
+	algo     string
+	expected string
+	sumsFile string
+	name     string
+}
+
+// VerifyChecksum returns a new ChecksumHandler
+// which verifies the input against the hex digest expected.
+// The algorithm can be given as a prefix, e.g. "blake2b:<hex>", or by WithAlgo,
+// otherwise it is chosen by the length of the digest: md5, sha256 or sha512.
+func VerifyChecksum(expected string) *ChecksumHandler {
+	algo, digest, ok := strings.Cut(expected, ":")
+	if !ok {
+		algo, digest = "", expected
+	}
+	return &ChecksumHandler{algo: algo, expected: strings.ToLower(strings.TrimSpace(digest))}
+}
+
+// VerifyChecksumFile returns a new ChecksumHandler which verifies the input against
+// the digest of name in sumsFile, e.g. a SHA256SUMS file, which is read using Context.FS.
+// Both the "<hex>  <name>" format of sha256sum
+// and the "SHA256 (<name>) = <hex>" format of BSD are supported.
+// If name is empty, the base name of the input file (e.g. from OpenFile) is used.
+func VerifyChecksumFile(sumsFile, name string) *ChecksumHandler {
+	return &ChecksumHandler{sumsFile: sumsFile, name: name}
+}
+
+// WithAlgo sets the algorithm instead of choosing it by the length of the digest.
+func (h *ChecksumHandler) WithAlgo(algo string) *ChecksumHandler {
+	h.algo = algo
+	return h
+}
+
+// Handle implements Handler.
+func (h *ChecksumHandler) Handle(c *Context, in any) (any, error) {
+	expected := h.expected
+	if h.sumsFile != "" {
+		name := h.name
+		if f, ok := in.(interface{ Name() string }); ok && name == "" {
+			name = filepath.Base(f.Name())
+		}
+		if name == "" {
+			return nil, errors.New("no file name to look up in the checksum file")
+		}
+
+		b, err := fs.ReadFile(c.FS(), c.path(h.sumsFile))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read checksum file")
+		}
+		if expected, err = lookupSum(b, name); err != nil {
+			return nil, err
+		}
+	}
+
+	algo := h.algo
+	if algo == "" {
+		var ok bool
+		if algo, ok = algoByLength(expected); !ok {
+			return nil, errors.Newf(
+				"unknown algorithm of the %d-character digest %q, set it by WithAlgo",
+				len(expected),
+				expected,
+			)
+		}
+	}
+	r, err := utils.Reader(in)
+	if err != nil {
+		return nil, err
+	}
+	hr, err := newHashReader(r, algo, func(sum string) error {
+		if sum != expected {
+			return errors.Wrapf(ErrChecksumMismatch, "%s expected %s, got %s", algo, expected, sum)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hr.next(c)
+}
+
+// hashReader computes the digest of the data read from r,
+// and calls onEOF with the hex digest at the end.
+type hashReader struct {
+	r     io.Reader
+	h     hash.Hash
+	onEOF func(sum string) error
+	sum   string
+	err   error
+}
+
+func newHashReader(r io.Reader, algo string, onEOF func(sum string) error) (*hashReader, error) {
+	h, err := newHash(algo)
+	if err != nil {
+		return nil, err
+	}
+	return &hashReader{r: r, h: h, onEOF: onEOF}, nil
+}
+
+func (r *hashReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.r.Read(p)
+	r.h.Write(p[:n])
+	if err == io.EOF {
+		r.sum = hex.EncodeToString(r.h.Sum(nil))
+		if cerr := r.onEOF(r.sum); cerr != nil {
+			err = cerr
+		}
+	}
+	// keep the error, so that onEOF is called once
+	r.err = err
+	return n, err
+}
+
+// next sends the reader to next handler, and drains the rest of the stream after it returns.
+func (r *hashReader) next(c *Context) (any, error) {
+	out, err := c.Next(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(io.Discard, r); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func newHash(algo string) (hash.Hash, error) {
+	switch strings.ToLower(algo) {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "md5":
+		return md5.New(), nil
+	case "blake2b":
+		return blake2b.New512(nil)
+	default:
+		return nil, errors.Newf("unsupported hash algorithm %q", algo)
+	}
+}
+
+// algoByLength returns the algorithm of the hex digest by its length.
+func algoByLength(digest string) (string, bool) {
+	switch len(digest) {
+	case 32:
+		return "md5", true
+	case 64:
+		return "sha256", true
+	case 128:
+		return "sha512", true
+	default:
+		return "", false
+	}
+}
+
+// lookupSum returns the hex digest of name in the checksum file.
+func lookupSum(b []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// BSD format: SHA256 (name) = hex
+		if i := strings.Index(line, " ("); i > 0 && strings.Contains(line, ") = ") {
+			n, sum, _ := strings.Cut(line[i+2:], ") = ")
+			if n == name {
+				return strings.ToLower(sum), nil
+			}
+			continue
+		}
+		sum, n, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		n = strings.TrimPrefix(strings.TrimLeft(n, " *"), "./")
+		if n == name {
+			return strings.ToLower(sum), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.Newf("no checksum of %s in the checksum file", name)
+}
@@ -0,0 +1,160 @@
+package yevna_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/tlipoca9/yevna"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - Hash", func() {
+	const (
+		content = "hello yevna\n"
+		digest  = "aaa5a539d3436118b92099481cd0a47958709ad76499b1a9fb415f748433d451"
+		other   = "b190adf54ecda2fcbbb2c0a2371d22d2b190adf54ecda2fcbbb2c0a2371d22d2"
+	)
+	var (
+		y   = yevna.New()
+		dir string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("computes digests while passing the stream through", func(ctx context.Context) {
+		var sum string
+		Expect(y.Run(ctx, yevna.Input(content), yevna.Hash("sha256").Digest(&sum))).To(Succeed())
+		Expect(sum).To(Equal(digest))
+
+		var got, fromContext string
+		err := y.Run(
+			ctx,
+			yevna.Input(content),
+			yevna.Hash("md5"),
+			yevna.ToStr(),
+			yevna.Output(&got),
+			yevna.HandlerFunc(func(c *yevna.Context, in any) (any, error) {
+				fromContext = c.Value(yevna.HashKey).(string)
+				return in, nil
+			}),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal(content))
+		Expect(fromContext).To(Equal("b190adf54ecda2fcbbb2c0a2371d22d2"))
+
+		for algo, n := range map[string]int{"sha512": 128, "blake2b": 128} {
+			var d string
+			Expect(y.Run(ctx, yevna.Input(content), yevna.Hash(algo).Digest(&d))).To(Succeed())
+			Expect(d).To(HaveLen(n))
+		}
+		Expect(func() { yevna.Hash("crc32") }).To(Panic())
+	})
+
+	It("verifies the checksum", func(ctx context.Context) {
+		path := filepath.Join(dir, "app")
+		err := y.Run(
+			ctx,
+			yevna.Input(content),
+			yevna.VerifyChecksum("sha256:"+digest),
+			yevna.WriteFile(path),
+		)
+		Expect(err).To(BeNil())
+		Expect(os.ReadFile(path)).To(Equal([]byte(content)))
+
+		err = y.Run(ctx, yevna.Input(content), yevna.VerifyChecksum(digest))
+		Expect(err).To(BeNil())
+
+		var blake string
+		Expect(y.Run(ctx, yevna.Input(content), yevna.Hash("blake2b").Digest(&blake))).To(Succeed())
+		Expect(
+			y.Run(ctx, yevna.Input(content), yevna.VerifyChecksum("blake2b:"+blake)),
+		).To(Succeed())
+		Expect(
+			y.Run(ctx, yevna.Input(content), yevna.VerifyChecksum(blake).WithAlgo("blake2b")),
+		).To(Succeed())
+
+		err = y.Run(ctx, yevna.Input(content), yevna.VerifyChecksum(digest[:40]))
+		Expect(err).To(MatchError(ContainSubstring("unknown algorithm of the 40-character digest")))
+	})
+
+	It("publishes the digest when a streaming handler follows", func(ctx context.Context) {
+		var sum, fromContext string
+		err := y.Run(
+			ctx,
+			yevna.Input(content),
+			yevna.Hash("sha256").Digest(&sum),
+			yevna.Grep("yevna", false),
+			yevna.ToStr(),
+			yevna.HandlerFunc(func(c *yevna.Context, in any) (any, error) {
+				fromContext = c.Value(yevna.HashKey).(string)
+				return in, nil
+			}),
+		)
+		Expect(err).To(BeNil())
+		Expect(sum).To(Equal(digest))
+		Expect(fromContext).To(Equal(digest))
+	})
+
+	It("fails on mismatch before the file is replaced", func(ctx context.Context) {
+		path := filepath.Join(dir, "app")
+		Expect(os.WriteFile(path, []byte("old"), 0644)).To(Succeed())
+
+		err := y.Run(
+			ctx,
+			yevna.Input("tampered"),
+			yevna.VerifyChecksum(digest),
+			yevna.WriteFile(path).Atomic(true),
+		)
+		Expect(err).To(MatchError(yevna.ErrChecksumMismatch))
+		Expect(os.ReadFile(path)).To(Equal([]byte("old")))
+
+		// the stream is drained after next handler returns
+		err = y.Run(ctx, yevna.Input("tampered"), yevna.VerifyChecksum(digest))
+		Expect(err).To(MatchError(yevna.ErrChecksumMismatch))
+	})
+
+	It("verifies against a checksum file", func(ctx context.Context) {
+		Expect(os.WriteFile(filepath.Join(dir, "app"), []byte(content), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "SHA256SUMS"), []byte(
+			other+"  other\n"+digest+" *app\n",
+		), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "BSDSUMS"), []byte(
+			"SHA256 (app) = "+digest+"\n",
+		), 0644)).To(Succeed())
+
+		err := y.Run(
+			ctx,
+			yevna.Chdir(dir),
+			yevna.OpenFile("app"),
+			yevna.VerifyChecksumFile("SHA256SUMS", ""),
+		)
+		Expect(err).To(BeNil())
+		err = y.Run(
+			ctx,
+			yevna.Chdir(dir),
+			yevna.Input(content),
+			yevna.VerifyChecksumFile("BSDSUMS", "app"),
+		)
+		Expect(err).To(BeNil())
+
+		err = y.Run(
+			ctx,
+			yevna.Chdir(dir),
+			yevna.Input(content),
+			yevna.VerifyChecksumFile("SHA256SUMS", "other"),
+		)
+		Expect(err).To(MatchError(yevna.ErrChecksumMismatch))
+		err = y.Run(
+			ctx,
+			yevna.Chdir(dir),
+			yevna.Input(content),
+			yevna.VerifyChecksumFile("SHA256SUMS", "missing"),
+		)
+		Expect(err).To(MatchError(ContainSubstring("no checksum of missing")))
+	})
+})
@@ -1,6 +1,7 @@
 package yevna
 
 import (
+	"io"
 	"net/http"
 )
 
@@ -27,13 +28,27 @@ func (h *HTTPClient) Do(fn func(c *Context, in any) (*http.Request, error)) Hand
 			return nil, err
 		}
 
-		out, err := c.Next(resp.Body)
+		out, err := c.Next(
+			&responseBody{ReadCloser: resp.Body, contentType: resp.Header.Get("Content-Type")},
+		)
 
 		resp.Body.Close()
 		return out, err
 	})
 }
 
+// responseBody is the body of the HTTP response,
+// it reports the Content-Type of the response, e.g. for UnmarshalAuto.
+type responseBody struct {
+	io.ReadCloser
+	contentType string
+}
+
+// ContentType returns the Content-Type header of the response.
+func (b *responseBody) ContentType() string {
+	return b.contentType
+}
+
 func HTTP(fn func(c *Context, in any) (*http.Request, error)) Handler {
 	return DefaultHTTPClient.Do(fn)
 }
@@ -0,0 +1,162 @@
+package yevna
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+	"github.com/itchyny/gojq"
+
+	"github.com/tlipoca9/yevna/utils"
+)
+
+// JQHandler is a Handler that evaluates a jq program against the input json.
+type JQHandler struct {
+	expr    string
+	args    map[string]any
+	vars    []string
+	raw     bool
+	collect bool
+}
+
+// JQ returns a new JQHandler that evaluates the jq program expr.
+// The input may contain a stream of json values, expr is evaluated against each of them.
+// By default, each result is sent to next handler as a newline-delimited json stream.
+func JQ(expr string) *JQHandler {
+	return &JQHandler{expr: expr}
+}
+
+// WithArg binds the variable $name to v, like `jq --arg name v`.
+func (h *JQHandler) WithArg(name string, v any) *JQHandler {
+	if h.args == nil {
+		h.args = make(map[string]any)
+	}
+	h.args[name] = v
+	return h
+}
+
+// WithVars binds the variables $name to Context.Value(name) for each name.
+func (h *JQHandler) WithVars(names ...string) *JQHandler {
+	h.vars = append(h.vars, names...)
+	return h
+}
+
+// Raw sets the raw output mode, like `jq -r`.
+// If raw is true, string results are written without quotes.
+// It has no effect if Collect is true.
+func (h *JQHandler) Raw(raw bool) *JQHandler {
+	h.raw = raw
+	return h
+}
+
+// Collect sets the collect mode.
+// If collect is true, all results are collected into a json array.
+func (h *JQHandler) Collect(collect bool) *JQHandler {
+	h.collect = collect
+	return h
+}
+
+// Handle implements Handler.
+func (h *JQHandler) Handle(c *Context, in any) (any, error) {
+	query, err := gojq.Parse(h.expr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse jq program")
+	}
+
+	names := make([]string, 0, len(h.args)+len(h.vars))
+	values := make([]any, 0, len(h.args)+len(h.vars))
+	for name, v := range h.args {
+		names = append(names, "$"+name)
+		values = append(values, v)
+	}
+	for _, name := range h.vars {
+		names = append(names, "$"+name)
+		values = append(values, c.Value(name))
+	}
+	for i := range values {
+		values[i], err = jqNormalize(values[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert variable %s", names[i])
+		}
+	}
+
+	code, err := gojq.Compile(query, gojq.WithVariables(names))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compile jq program")
+	}
+
+	r, err := utils.Reader(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []any
+	dec := json.NewDecoder(r)
+	for {
+		var v any
+		err = dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid json")
+		}
+
+		iter := code.RunWithContext(c.Context(), v, values...)
+		for {
+			out, ok := iter.Next()
+			if !ok {
+				break
+			}
+			if err, ok := out.(error); ok {
+				var herr *gojq.HaltError
+				if errors.As(err, &herr) && herr.Value() == nil {
+					break
+				}
+				return nil, errors.Wrap(err, "failed to evaluate jq program")
+			}
+			results = append(results, out)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if h.collect {
+		if results == nil {
+			results = []any{}
+		}
+		if err = enc.Encode(results); err != nil {
+			return nil, errors.Wrap(err, "failed to encode result")
+		}
+		return &buf, nil
+	}
+	for _, out := range results {
+		if s, ok := out.(string); ok && h.raw {
+			buf.WriteString(s)
+			buf.WriteByte('\n')
+			continue
+		}
+		if err = enc.Encode(out); err != nil {
+			return nil, errors.Wrap(err, "failed to encode result")
+		}
+	}
+	return &buf, nil
+}
+
+// jqNormalize converts v to the types accepted by gojq
+// by encoding it to json and decoding it back.
+func jqNormalize(v any) (any, error) {
+	switch v.(type) {
+	case nil, bool, int, float64, string:
+		return v, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
@@ -0,0 +1,108 @@
+package yevna_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/tlipoca9/yevna"
+	"github.com/tlipoca9/yevna/parser"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - JQ", func() {
+	var buf *bytes.Buffer
+	y := yevna.New()
+	pods := `{"items": [
+		{"name": "foo", "status": "Running"},
+		{"name": "bar", "status": "Pending"},
+		{"name": "baz", "status": "Running"}
+	]}`
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+	})
+
+	It("should evaluate jq program", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(pods),
+			yevna.JQ(`.items | map(select(.status == "Running")) | length`),
+			yevna.Tee(buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal("2\n"))
+	})
+
+	It("should emit json stream", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(pods),
+			yevna.JQ(`.items[] | {name}`),
+			yevna.Tee(buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(
+			buf.String(),
+		).To(Equal(`{"name":"foo"}` + "\n" + `{"name":"bar"}` + "\n" + `{"name":"baz"}` + "\n"))
+	})
+
+	It("should emit raw strings", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(pods),
+			yevna.JQ(`.items[].name`).Raw(true),
+			yevna.Tee(buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal("foo\nbar\nbaz\n"))
+	})
+
+	It("should collect results", func(ctx context.Context) {
+		var got []string
+		err := y.Run(
+			ctx,
+			yevna.Input(pods),
+			yevna.JQ(`.items[].name`).Collect(true),
+			yevna.Unmarshal(parser.JSON(), &got),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal([]string{"foo", "bar", "baz"}))
+	})
+
+	It("should bind variables", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Value("status", "Pending"),
+			yevna.Input(pods),
+			yevna.JQ(`.items[] | select(.status == $status and .name != $skip) | .name`).
+				WithVars("status").
+				WithArg("skip", "qux").
+				Raw(true),
+			yevna.Tee(buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal("bar\n"))
+	})
+
+	It("should evaluate each input of json stream", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"a": 1} {"a": 2}`),
+			yevna.JQ(`.a`),
+			yevna.Tee(buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal("1\n2\n"))
+	})
+
+	It("should fail on invalid program", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(pods),
+			yevna.JQ(`.items[`),
+		)
+		Expect(err).NotTo(BeNil())
+	})
+})
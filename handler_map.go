@@ -0,0 +1,169 @@
+package yevna
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/tlipoca9/yevna/parser"
+	"github.com/tlipoca9/yevna/utils"
+)
+
+// TypeError is returned by the typed handlers if the input can't be converted to the expected type.
+type TypeError struct {
+	// Step is the name and the position of the handler in the chain, e.g. "main.parseConfig (#3)".
+	Step string
+	// Expected is the type the handler expects.
+	Expected reflect.Type
+	// Actual is the type of the input, it is nil if the input is nil.
+	Actual reflect.Type
+	// Err is the error of the conversion, it may be nil.
+	Err error
+}
+
+func (e *TypeError) Error() string {
+	actual := "<nil>"
+	if e.Actual != nil {
+		actual = e.Actual.String()
+	}
+	msg := fmt.Sprintf("%s: expected %s, got %s from previous handler", e.Step, e.Expected, actual)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *TypeError) Unwrap() error {
+	return e.Err
+}
+
+// MapHandler is a Handler that calls a typed function.
+// The input is converted to In by the following rules:
+//   - In or *In: used as is.
+//   - []byte, string: read from io.Reader, []byte, string or fmt.Stringer.
+//   - io.Reader: see utils.Reader.
+//   - any other type: decoded by parser.JSON from io.Reader, []byte or string.
+//   - nil: the zero value if In is a pointer, interface, map, slice, func or chan.
+//
+// If the conversion fails, it returns *TypeError.
+type MapHandler[In, Out any] struct {
+	name string
+	fn   func(c *Context, in In) (Out, error)
+}
+
+// Map returns a new MapHandler which sends the result of fn to next handler.
+func Map[In, Out any](fn func(c *Context, in In) (Out, error)) *MapHandler[In, Out] {
+	return &MapHandler[In, Out]{name: funcName(fn), fn: fn}
+}
+
+// Tap returns a new MapHandler which calls fn, and sends the converted input to next handler.
+func Tap[In any](fn func(c *Context, in In) error) *MapHandler[In, In] {
+	h := Map(func(c *Context, in In) (In, error) {
+		return in, fn(c, in)
+	})
+	h.name = funcName(fn)
+	return h
+}
+
+// Named sets the name of the step used in errors, the default is the name of the function.
+func (h *MapHandler[In, Out]) Named(name string) *MapHandler[In, Out] {
+	h.name = name
+	return h
+}
+
+// Handle implements Handler.
+func (h *MapHandler[In, Out]) Handle(c *Context, in any) (any, error) {
+	v, err := As[In](in)
+	if err != nil {
+		if te, ok := err.(*TypeError); ok {
+			te.Step = fmt.Sprintf("%s (#%d)", h.name, c.index)
+		}
+		return nil, err
+	}
+	return h.fn(c, v)
+}
+
+// As converts the input to T by the rules of MapHandler.
+// If the conversion fails, it returns *TypeError.
+func As[T any](in any) (T, error) {
+	var zero T
+	if v, ok := in.(T); ok {
+		return v, nil
+	}
+	if p, ok := in.(*T); ok && p != nil {
+		return *p, nil
+	}
+
+	t := reflect.TypeFor[T]()
+	fail := func(err error) (T, error) {
+		return zero, &TypeError{Step: "As", Expected: t, Actual: reflect.TypeOf(in), Err: err}
+	}
+	if in == nil {
+		switch t.Kind() {
+		case reflect.Pointer,
+			reflect.Interface,
+			reflect.Map,
+			reflect.Slice,
+			reflect.Func,
+			reflect.Chan:
+			return zero, nil
+		}
+		return fail(nil)
+	}
+
+	switch t {
+	case reflect.TypeFor[[]byte]():
+		b, err := readAll(in)
+		if err != nil {
+			return fail(err)
+		}
+		return any(b).(T), nil
+	case reflect.TypeFor[string]():
+		b, err := readAll(in)
+		if err != nil {
+			return fail(err)
+		}
+		return any(string(b)).(T), nil
+	case reflect.TypeFor[io.Reader]():
+		r, err := utils.Reader(in)
+		if err != nil {
+			return fail(err)
+		}
+		return any(r).(T), nil
+	}
+
+	switch in.(type) {
+	case io.Reader, []byte, string:
+	default:
+		return fail(nil)
+	}
+	b, err := readAll(in)
+	if err != nil {
+		return fail(err)
+	}
+	var v T
+	if t.Kind() == reflect.Pointer {
+		v = reflect.New(t.Elem()).Interface().(T)
+		err = parser.JSON().Unmarshal(b, v)
+	} else {
+		err = parser.JSON().Unmarshal(b, &v)
+	}
+	if err != nil {
+		return fail(errors.Wrap(err, "failed to unmarshal json"))
+	}
+	return v, nil
+}
+
+// funcName returns the name of the function without the module path, e.g. "main.parseConfig".
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "unknown"
+	}
+	name := f.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -0,0 +1,131 @@
+package yevna_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/tlipoca9/yevna"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type release struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+func releaseTag(_ *yevna.Context, r release) (string, error) {
+	return r.Name + ":" + r.Version, nil
+}
+
+var _ = Describe("Handler - Map", func() {
+	y := yevna.New()
+
+	run := func(ctx context.Context, handlers ...yevna.Handler) (any, error) {
+		var got any
+		handlers = append(handlers, yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+			got = in
+			return in, nil
+		}))
+		err := y.Run(ctx, handlers...)
+		return got, err
+	}
+
+	It("decodes json into the input type", func(ctx context.Context) {
+		const in = `{"name": "yevna", "version": "v1.0.0"}`
+		for _, input := range []any{in, []byte(in), strings.NewReader(in)} {
+			got, err := run(ctx, yevna.Input(input), yevna.Map(releaseTag))
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal("yevna:v1.0.0"))
+		}
+
+		got, err := run(
+			ctx,
+			yevna.Input(in),
+			yevna.Map(func(_ *yevna.Context, r *release) (string, error) {
+				return r.Name, nil
+			}),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("yevna"))
+
+		got, err = run(ctx, yevna.Input(&release{Name: "ptr"}), yevna.Map(releaseTag))
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("ptr:"))
+	})
+
+	It("converts readers to bytes and strings", func(ctx context.Context) {
+		got, err := run(
+			ctx,
+			yevna.Input(strings.NewReader("hello")),
+			yevna.Map(func(_ *yevna.Context, s string) ([]byte, error) {
+				return []byte(strings.ToUpper(s)), nil
+			}),
+			yevna.Map(func(_ *yevna.Context, b []byte) (int, error) {
+				return len(b), nil
+			}),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal(5))
+
+		got, err = run(
+			ctx,
+			yevna.Input("hi"),
+			yevna.Map(func(_ *yevna.Context, r io.Reader) (string, error) {
+				b, err := io.ReadAll(r)
+				return string(b), err
+			}),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("hi"))
+	})
+
+	It("taps the input", func(ctx context.Context) {
+		var seen release
+		got, err := run(
+			ctx,
+			yevna.Input(`{"name": "tap"}`),
+			yevna.Tap(func(_ *yevna.Context, r release) error {
+				seen = r
+				return nil
+			}),
+		)
+		Expect(err).To(BeNil())
+		Expect(seen.Name).To(Equal("tap"))
+		Expect(got).To(Equal(release{Name: "tap"}))
+	})
+
+	It("reports type errors with the step", func(ctx context.Context) {
+		_, err := run(ctx, yevna.Input(42), yevna.Map(releaseTag))
+		var te *yevna.TypeError
+		Expect(errors.As(err, &te)).To(BeTrue())
+		Expect(te.Expected).To(Equal(reflect.TypeFor[release]()))
+		Expect(te.Actual).To(Equal(reflect.TypeFor[int]()))
+		Expect(err).To(MatchError(
+			"yevna_test.releaseTag (#1): expected yevna_test.release, " +
+				"got int from previous handler",
+		))
+
+		_, err = run(ctx, yevna.Input("not json"), yevna.Map(releaseTag).Named("parse release"))
+		Expect(err).To(MatchError(HavePrefix(
+			"parse release (#1): expected yevna_test.release, got string from previous handler: " +
+				"failed to unmarshal json",
+		)))
+
+		_, err = run(ctx, yevna.Map(releaseTag))
+		Expect(err).To(MatchError(ContainSubstring("got <nil>")))
+	})
+
+	It("converts values", func() {
+		v, err := yevna.As[map[string]int](`{"a": 1}`)
+		Expect(err).To(BeNil())
+		Expect(v).To(Equal(map[string]int{"a": 1}))
+
+		_, err = yevna.As[int](struct{}{})
+		Expect(err).To(MatchError("As: expected int, got struct {} from previous handler"))
+	})
+})
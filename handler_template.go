@@ -0,0 +1,160 @@
+package yevna
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+	"github.com/goccy/go-yaml"
+)
+
+// TemplateHandler is a Handler that executes a text/template with the input as dot.
+//
+// Besides the builtin functions of text/template, the template can use
+//   - toYaml, toJson, toPrettyJson: encode the value, without the trailing newline.
+//   - indent n s, nindent n s: indent each line of s by n spaces, nindent adds a leading newline.
+//   - default d v: v if it is not empty, otherwise d.
+//   - required msg v: v if it is not empty, otherwise fails with msg.
+//   - env name: the environment variable.
+//   - b64enc, b64dec, quote, upper, lower, trim: string helpers.
+//   - workdir: the working directory of Context.
+//   - value key: the Context value of key.
+//
+// It sends the rendered text as *bytes.Buffer to next handler.
+type TemplateHandler struct {
+	name   string
+	text   string
+	path   string
+	funcs  template.FuncMap
+	strict bool
+}
+
+// Template returns a new TemplateHandler which executes the template text.
+func Template(text string) *TemplateHandler {
+	return &TemplateHandler{name: "template", text: text}
+}
+
+// TemplateFile returns a new TemplateHandler which executes the template file.
+// If the path is relative, it is relative to the working directory.
+// The file is read using Context.FS.
+func TemplateFile(path string) *TemplateHandler {
+	return &TemplateHandler{name: filepath.Base(path), path: path}
+}
+
+// WithFuncs adds the functions to the template, they override the default functions.
+func (h *TemplateHandler) WithFuncs(funcs template.FuncMap) *TemplateHandler {
+	if h.funcs == nil {
+		h.funcs = make(template.FuncMap)
+	}
+	for name, f := range funcs {
+		h.funcs[name] = f
+	}
+	return h
+}
+
+// Strict sets the strict mode.
+// If strict is true, it fails when the template accesses a missing map key.
+func (h *TemplateHandler) Strict(strict bool) *TemplateHandler {
+	h.strict = strict
+	return h
+}
+
+// Handle implements Handler.
+func (h *TemplateHandler) Handle(c *Context, in any) (any, error) {
+	text := h.text
+	if h.path != "" {
+		b, err := fs.ReadFile(c.FS(), c.path(h.path))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read template file")
+		}
+		text = string(b)
+	}
+
+	t := template.New(h.name).Funcs(templateFuncs(c)).Funcs(h.funcs)
+	if h.strict {
+		t = t.Option("missingkey=error")
+	}
+	t, err := t.Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse template")
+	}
+
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, in); err != nil {
+		return nil, errors.Wrap(err, "failed to execute template")
+	}
+	return &buf, nil
+}
+
+// templateFuncs returns the default functions of TemplateHandler.
+func templateFuncs(c *Context) template.FuncMap {
+	indent := func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	}
+	return template.FuncMap{
+		"toYaml": func(v any) (string, error) {
+			b, err := yaml.Marshal(v)
+			return strings.TrimSuffix(string(b), "\n"), err
+		},
+		"toJson": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"toPrettyJson": func(v any) (string, error) {
+			b, err := json.MarshalIndent(v, "", "  ")
+			return string(b), err
+		},
+		"indent": indent,
+		"nindent": func(n int, s string) string {
+			return "\n" + indent(n, s)
+		},
+		"default": func(d any, v ...any) any {
+			if len(v) == 0 || isEmpty(v[0]) {
+				return d
+			}
+			return v[0]
+		},
+		"required": func(msg string, v any) (any, error) {
+			if isEmpty(v) {
+				return nil, errors.New(msg)
+			}
+			return v, nil
+		},
+		"env": os.Getenv,
+		"b64enc": func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		},
+		"b64dec": func(s string) (string, error) {
+			b, err := base64.StdEncoding.DecodeString(s)
+			return string(b), err
+		},
+		"quote":   strconv.Quote,
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+		"trim":    strings.TrimSpace,
+		"workdir": func() string { return c.Workdir() },
+		"value":   func(key string) any { return c.Value(key) },
+	}
+}
+
+// isEmpty reports whether v is nil or the zero value of its type, or an empty collection.
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
@@ -0,0 +1,103 @@
+package yevna_test
+
+import (
+	"bytes"
+	"context"
+	"text/template"
+
+	"github.com/tlipoca9/yevna"
+	"github.com/tlipoca9/yevna/parser"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - Template", func() {
+	y := yevna.New()
+
+	render := func(ctx context.Context, in any, h yevna.Handler) (string, error) {
+		var buf bytes.Buffer
+		err := y.Run(ctx, yevna.Input(in), h, yevna.Output(&buf))
+		return buf.String(), err
+	}
+
+	It("renders the input as dot", func(ctx context.Context) {
+		var data map[string]any
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"name": "web", "ports": [80, 443], "labels": {"app": "web"}}`),
+			yevna.Unmarshal(parser.JSON(), &data),
+		)
+		Expect(err).To(BeNil())
+
+		got, err := render(ctx, data, yevna.Template(`
+name: {{ .name | upper }}
+labels:{{ toYaml .labels | nindent 2 }}
+ports: {{ toJson .ports }}
+replicas: {{ default 1 .replicas }}
+secret: {{ b64enc .name }}
+`[1:]))
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal(`
+name: WEB
+labels:
+  app: web
+ports: [80,443]
+replicas: 1
+secret: d2Vi
+`[1:]))
+	})
+
+	It("accesses the Context", func(ctx context.Context) {
+		GinkgoT().Setenv("YEVNA_TEMPLATE_TEST", "hello")
+		var buf bytes.Buffer
+		err := y.Run(
+			ctx,
+			yevna.Chdir("/tmp"),
+			yevna.Value("greeting", "hi"),
+			yevna.Template(`{{ env "YEVNA_TEMPLATE_TEST" }} {{ value "greeting" }} {{ workdir }}`),
+			yevna.Output(&buf),
+		)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal("hello hi /tmp"))
+	})
+
+	It("fails on missing keys in strict mode", func(ctx context.Context) {
+		data := map[string]any{"name": "web"}
+
+		got, err := render(ctx, data, yevna.Template(`{{ .missing }}`))
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("<no value>"))
+
+		_, err = render(ctx, data, yevna.Template(`{{ .missing }}`).Strict(true))
+		Expect(err).To(HaveOccurred())
+
+		_, err = render(ctx, data, yevna.Template(`{{ required "image is required" .image }}`))
+		Expect(err).To(MatchError(ContainSubstring("image is required")))
+	})
+
+	It("uses custom functions", func(ctx context.Context) {
+		got, err := render(ctx, "web", yevna.Template(`{{ greet . }}`).WithFuncs(template.FuncMap{
+			"greet": func(s string) string { return "hello " + s },
+		}))
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal("hello web"))
+	})
+
+	It("renders the template file", func(ctx context.Context) {
+		got, err := render(
+			ctx,
+			map[string]any{"name": "web"},
+			yevna.TemplateFile("tests/test.tmpl"),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(Equal(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+data:
+  config.json: "{\"name\":\"web\"}"
+`[1:]))
+	})
+})
@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 
 	"github.com/goccy/go-json"
+	"github.com/goccy/go-yaml"
 
 	. "github.com/onsi/ginkgo/v2"
 )
@@ -37,5 +38,15 @@ var _ = BeforeSuite(func() {
 		}
 		_, _ = w.Write(buf)
 	})
+	g.HandleFunc("/ipinfo.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		buf, err := yaml.Marshal(ipInfoMap)
+		if err != nil {
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		_, _ = w.Write(buf)
+	})
 	svc = httptest.NewServer(g)
 })
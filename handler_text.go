@@ -0,0 +1,464 @@
+package yevna
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/tlipoca9/yevna/utils"
+)
+
+// maxLineSize is the maximum size of a line read by the text handlers.
+const maxLineSize = 1 << 20
+
+// Grep returns a Handler that keeps the lines matching the regular expression pattern.
+// If invert is true, it keeps the lines not matching pattern instead.
+// It streams the lines as io.Reader to next handler.
+func Grep(pattern string, invert bool) Handler {
+	re := regexp.MustCompile(pattern)
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		return streamLines(c, in, func(s *bufio.Scanner, w io.Writer) error {
+			for s.Scan() {
+				if re.Match(s.Bytes()) == invert {
+					continue
+				}
+				if err := writeLine(w, s.Text()); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Replace returns a Handler that replaces the matches of the regular expression pattern
+// in each line with repl.
+// Inside repl, $ signs are interpreted as in regexp.Regexp.Expand, e.g. $1 for the first submatch.
+// It streams the lines as io.Reader to next handler.
+func Replace(pattern, repl string) Handler {
+	re := regexp.MustCompile(pattern)
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		return streamLines(c, in, func(s *bufio.Scanner, w io.Writer) error {
+			for s.Scan() {
+				if err := writeLine(w, re.ReplaceAllString(s.Text(), repl)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Head returns a Handler that keeps the first n lines.
+// It stops reading the input after n lines.
+// It streams the lines as io.Reader to next handler.
+func Head(n int) Handler {
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		return streamLines(c, in, func(s *bufio.Scanner, w io.Writer) error {
+			for i := 0; i < n && s.Scan(); i++ {
+				if err := writeLine(w, s.Text()); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Tail returns a Handler that keeps the last n lines.
+// It keeps at most n lines in memory.
+// It streams the lines as io.Reader to next handler.
+func Tail(n int) Handler {
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		return streamLines(c, in, func(s *bufio.Scanner, w io.Writer) error {
+			if n <= 0 {
+				return nil
+			}
+			ring := make([]string, 0, n)
+			next := 0
+			for s.Scan() {
+				if len(ring) < n {
+					ring = append(ring, s.Text())
+					continue
+				}
+				ring[next] = s.Text()
+				next = (next + 1) % n
+			}
+			for i := range ring {
+				if err := writeLine(w, ring[(next+i)%len(ring)]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// SortOptions is the options of Sort.
+type SortOptions struct {
+	// Reverse sorts in descending order.
+	Reverse bool
+	// Numeric compares the keys as numbers, the keys which are not numbers are treated as 0.
+	Numeric bool
+	// Unique keeps only the first line of the lines with equal keys.
+	Unique bool
+	// Key is the 1-based field used as the sort key, 0 means the whole line.
+	Key int
+	// Sep is the field separator, the fields are separated by white spaces if it is empty.
+	Sep string
+}
+
+// Sort returns a Handler that sorts the lines stably.
+// It reads all lines before sending any.
+// It streams the lines as io.Reader to next handler.
+func Sort(opts SortOptions) Handler {
+	key := func(line string) string {
+		if opts.Key <= 0 {
+			return line
+		}
+		fields := splitFields(line, opts.Sep)
+		if opts.Key > len(fields) {
+			return ""
+		}
+		return fields[opts.Key-1]
+	}
+	compare := func(a, b string) int {
+		if !opts.Numeric {
+			return strings.Compare(a, b)
+		}
+		x, _ := strconv.ParseFloat(strings.TrimSpace(a), 64)
+		y, _ := strconv.ParseFloat(strings.TrimSpace(b), 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		return streamLines(c, in, func(s *bufio.Scanner, w io.Writer) error {
+			var lines []string
+			for s.Scan() {
+				lines = append(lines, s.Text())
+			}
+			slices.SortStableFunc(lines, func(a, b string) int {
+				if opts.Reverse {
+					return compare(key(b), key(a))
+				}
+				return compare(key(a), key(b))
+			})
+			for i, line := range lines {
+				if opts.Unique && i > 0 && compare(key(lines[i-1]), key(line)) == 0 {
+					continue
+				}
+				if err := writeLine(w, line); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Uniq returns a Handler that drops the adjacent duplicate lines.
+// If count is true, the lines are prefixed by the number of occurrences like "uniq -c".
+// It streams the lines as io.Reader to next handler.
+func Uniq(count bool) Handler {
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		return streamLines(c, in, func(s *bufio.Scanner, w io.Writer) error {
+			var (
+				prev string
+				n    int
+			)
+			flush := func() error {
+				if n == 0 {
+					return nil
+				}
+				if count {
+					return writeLine(w, fmt.Sprintf("%7d %s", n, prev))
+				}
+				return writeLine(w, prev)
+			}
+			for s.Scan() {
+				if n > 0 && s.Text() == prev {
+					n++
+					continue
+				}
+				if err := flush(); err != nil {
+					return err
+				}
+				prev, n = s.Text(), 1
+			}
+			return flush()
+		})
+	})
+}
+
+// Cut returns a Handler that keeps the 1-based fields of each line separated by sep, joined by sep.
+// The fields are separated by tab if sep is empty.
+// The lines without sep are kept unchanged like "cut -f".
+// It streams the lines as io.Reader to next handler.
+func Cut(fields []int, sep string) Handler {
+	if sep == "" {
+		sep = "\t"
+	}
+	return HandlerFunc(func(c *Context, in any) (any, error) {
+		return streamLines(c, in, func(s *bufio.Scanner, w io.Writer) error {
+			for s.Scan() {
+				line := s.Text()
+				if strings.Contains(line, sep) {
+					all := strings.Split(line, sep)
+					cut := make([]string, 0, len(fields))
+					for _, f := range fields {
+						if f > 0 && f <= len(all) {
+							cut = append(cut, all[f-1])
+						}
+					}
+					line = strings.Join(cut, sep)
+				}
+				if err := writeLine(w, line); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// WordCount is the result of WC.
+type WordCount struct {
+	Lines int
+	Words int
+	Bytes int
+}
+
+// String returns the counts like "wc".
+func (wc WordCount) String() string {
+	return fmt.Sprintf("%d %d %d", wc.Lines, wc.Words, wc.Bytes)
+}
+
+// WC returns a Handler that counts the newlines, words and bytes of the input like "wc".
+// It sends WordCount to next handler.
+func WC() Handler {
+	return HandlerFunc(func(_ *Context, in any) (any, error) {
+		r, err := utils.Reader(in)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			wc     WordCount
+			inWord bool
+			buf    = make([]byte, 32*1024)
+		)
+		for {
+			n, err := r.Read(buf)
+			for _, b := range buf[:n] {
+				switch b {
+				case '\n':
+					wc.Lines++
+					inWord = false
+				case ' ', '\t', '\r', '\v', '\f':
+					inWord = false
+				default:
+					if !inWord {
+						wc.Words++
+					}
+					inWord = true
+				}
+			}
+			wc.Bytes += n
+			if err == io.EOF {
+				return wc, nil
+			}
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read")
+			}
+		}
+	})
+}
+
+// LineHandler is a Handler that transforms the input line by line.
+// The lines are read lazily, so next handler sees each line as soon as it is read,
+// e.g. the output of Exec("kubectl", "logs", "-f").
+//
+// It streams the lines as io.Reader to next handler.
+type LineHandler struct {
+	cb           func(i int, line string) (string, bool, error)
+	maxTokenSize int
+	keepEndings  bool
+}
+
+// ForEachLine returns a new LineHandler which replaces each line with the result of cb.
+// i is the 0-based index of the line.
+func ForEachLine(cb func(i int, line string) string) *LineHandler {
+	return FilterLines(func(i int, line string) (string, bool, error) {
+		return cb(i, line), true, nil
+	})
+}
+
+// FilterLines returns a new LineHandler which replaces each line with the result of cb.
+// If keep is false, the line is dropped; if err is not nil, the stream fails with err.
+// i is the 0-based index of the line, the dropped lines are counted.
+func FilterLines(cb func(i int, line string) (out string, keep bool, err error)) *LineHandler {
+	return &LineHandler{cb: cb, maxTokenSize: maxLineSize}
+}
+
+// MaxTokenSize sets the maximum size of a line, the default is 1 MiB.
+// The stream fails if a line is longer.
+func (h *LineHandler) MaxTokenSize(n int) *LineHandler {
+	h.maxTokenSize = n
+	return h
+}
+
+// KeepLineEndings sets whether to keep the original line endings,
+// "\n", "\r\n" or none for the last line. By default, each line ends with "\n".
+// The callback always gets the line without the line ending.
+func (h *LineHandler) KeepLineEndings(keep bool) *LineHandler {
+	h.keepEndings = keep
+	return h
+}
+
+// Handle implements Handler.
+func (h *LineHandler) Handle(c *Context, in any) (any, error) {
+	return stream(c, in, func(r io.Reader, w io.Writer) error {
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, h.maxTokenSize)
+		s.Split(scanLinesWithEndings)
+		for i := 0; s.Scan(); i++ {
+			token := s.Text()
+			line := strings.TrimSuffix(strings.TrimSuffix(token, "\n"), "\r")
+			ending := "\n"
+			if h.keepEndings {
+				ending = token[len(line):]
+			}
+
+			out, keep, err := h.cb(i, line)
+			if err != nil {
+				return errors.Wrapf(err, "failed to handle line %d", i)
+			}
+			if !keep {
+				continue
+			}
+			if _, err = io.WriteString(w, out+ending); err != nil {
+				return err
+			}
+		}
+		return errors.Wrap(s.Err(), "failed to scan")
+	})
+}
+
+// scanLinesWithEndings is a bufio.SplitFunc like bufio.ScanLines, but keeps the line endings.
+func scanLinesWithEndings(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// errStreamClosed is the error of reading the output of a stream handler after the chain returns.
+var errStreamClosed = errors.New("stream is closed after the chain returns")
+
+// stream calls fn with the input and the writer of the output in a goroutine,
+// and sends the reader of the output to next handler, see pipe.
+// If fn returns before reading the whole input, e.g. Head,
+// the input is closed if it is an io.Closer,
+// so that the upstream, e.g. the stdout of Exec, isn't blocked on a full pipe.
+func stream(c *Context, in any, fn func(r io.Reader, w io.Writer) error) (any, error) {
+	r, err := utils.Reader(in)
+	if err != nil {
+		return nil, err
+	}
+
+	return pipe(c, func(w io.Writer) error {
+		er := &eofReader{r: r}
+		err := fn(er, w)
+		if closer, ok := r.(io.Closer); ok && !er.eof {
+			closer.Close()
+		}
+		return err
+	})
+}
+
+// pipe calls fn with the writer of the output in a goroutine,
+// and sends the reader of the output to next handler.
+// The writer is not buffered, so next handler sees each write as soon as fn makes it.
+//
+// The output is closed when the chain returns or the context is canceled,
+// so fn never blocks forever if next handlers don't read the whole output.
+func pipe(c *Context, fn func(w io.Writer) error) (any, error) {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(fn(pw))
+	}()
+
+	if ctx := c.Context(); ctx != nil {
+		stop := context.AfterFunc(ctx, func() {
+			pw.CloseWithError(ctx.Err())
+		})
+		defer stop()
+	}
+	out, err := c.Next(pr)
+	pr.CloseWithError(errStreamClosed)
+	<-done
+	return out, err
+}
+
+// eofReader records whether the reader is read to the end.
+type eofReader struct {
+	r   io.Reader
+	eof bool
+}
+
+func (r *eofReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+// streamLines scans the lines of the input and calls fn in a goroutine,
+// fn writes the output lines to w. See stream.
+func streamLines(c *Context, in any, fn func(s *bufio.Scanner, w io.Writer) error) (any, error) {
+	return stream(c, in, func(r io.Reader, w io.Writer) error {
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, maxLineSize)
+		if err := fn(s, w); err != nil {
+			return err
+		}
+		return errors.Wrap(s.Err(), "failed to scan")
+	})
+}
+
+// writeLine writes the line with a trailing newline.
+func writeLine(w io.Writer, line string) error {
+	_, err := io.WriteString(w, line+"\n")
+	return err
+}
+
+// splitFields splits the line by sep, or by white spaces if sep is empty.
+func splitFields(line, sep string) []string {
+	if sep == "" {
+		return strings.Fields(line)
+	}
+	return strings.Split(line, sep)
+}
@@ -0,0 +1,207 @@
+package yevna_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/tlipoca9/yevna"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - Text", func() {
+	y := yevna.New()
+
+	run := func(ctx context.Context, in string, handlers ...yevna.Handler) string {
+		var got string
+		handlers = append([]yevna.Handler{yevna.Input(in)}, handlers...)
+		handlers = append(handlers, yevna.ToStr(), yevna.Output(&got))
+		Expect(y.Run(ctx, handlers...)).To(Succeed())
+		return got
+	}
+
+	const logs = "INFO start\nWARN disk 91%\nINFO ready\nERROR disk full\n"
+
+	DescribeTable(
+		"transforms lines",
+		func(ctx context.Context, in string, h yevna.Handler, expected string) {
+			Expect(run(ctx, in, h)).To(Equal(expected))
+		},
+		Entry("Grep", logs, yevna.Grep(`^(WARN|ERROR)`, false), "WARN disk 91%\nERROR disk full\n"),
+		Entry("Grep invert", logs, yevna.Grep(`^INFO`, true), "WARN disk 91%\nERROR disk full\n"),
+		Entry(
+			"Replace",
+			logs,
+			yevna.Replace(`^(\w+) `, "[$1] "),
+			"[INFO] start\n[WARN] disk 91%\n[INFO] ready\n[ERROR] disk full\n",
+		),
+		Entry("Head", logs, yevna.Head(2), "INFO start\nWARN disk 91%\n"),
+		Entry("Head more than input", "a", yevna.Head(2), "a\n"),
+		Entry("Tail", logs, yevna.Tail(2), "INFO ready\nERROR disk full\n"),
+		Entry("Tail more than input", "a\nb\n", yevna.Tail(3), "a\nb\n"),
+		Entry("Tail zero", "a\nb\n", yevna.Tail(0), ""),
+		Entry("Sort", "b\nc\na\n", yevna.Sort(yevna.SortOptions{}), "a\nb\nc\n"),
+		Entry(
+			"Sort reverse numeric by key",
+			"x 10\ny 9\nz 100\n",
+			yevna.Sort(
+				yevna.SortOptions{Numeric: true, Reverse: true, Key: 2},
+			),
+			"z 100\nx 10\ny 9\n",
+		),
+		Entry("Sort unique by key", "a,1\nb,2\nc,1\n",
+			yevna.Sort(yevna.SortOptions{Unique: true, Key: 2, Sep: ","}), "a,1\nb,2\n"),
+		Entry("Uniq", "a\na\nb\na\n", yevna.Uniq(false), "a\nb\na\n"),
+		Entry("Uniq count", "a\na\nb\n", yevna.Uniq(true), "      2 a\n      1 b\n"),
+		Entry(
+			"Cut",
+			"root:x:0:0\nnobody:x:65534:65534\nplain\n",
+			yevna.Cut([]int{1, 3}, ":"),
+			"root:0\nnobody:65534\nplain\n",
+		),
+		Entry("Cut tab", "a\tb\tc\n", yevna.Cut([]int{2}, ""), "b\n"),
+	)
+
+	It("chains like a shell pipeline", func(ctx context.Context) {
+		got := run(ctx, logs,
+			yevna.Cut([]int{1}, " "),
+			yevna.Sort(yevna.SortOptions{}),
+			yevna.Uniq(true),
+			yevna.Sort(yevna.SortOptions{Numeric: true, Reverse: true, Key: 1}),
+			yevna.Head(1),
+		)
+		Expect(got).To(Equal("      2 INFO\n"))
+	})
+
+	It("stops reading after Head", func(ctx context.Context) {
+		pr, pw := io.Pipe()
+		go func() {
+			for {
+				if _, err := io.WriteString(pw, "y\n"); err != nil {
+					return
+				}
+			}
+		}()
+		Expect(
+			run(ctx, "", yevna.Input(pr), yevna.Grep("y", false), yevna.Head(3)),
+		).To(Equal("y\ny\ny\n"))
+	})
+
+	It("stops the command after Head", func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		var buf bytes.Buffer
+		err := y.Run(ctx, yevna.Exec("seq", "1", "1000000"), yevna.Head(1), yevna.Tee(&buf))
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal("1\n"))
+	})
+
+	It("doesn't leak goroutines if the output isn't read", func(ctx context.Context) {
+		before := runtime.NumGoroutine()
+		for range 10 {
+			Expect(y.Run(ctx, yevna.Input(logs), yevna.Grep("disk", false))).To(Succeed())
+		}
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", before))
+	})
+
+	It("counts like wc", func(ctx context.Context) {
+		var wc yevna.WordCount
+		err := y.Run(ctx, yevna.Input(logs), yevna.WC(), yevna.Output(&wc))
+		Expect(err).To(BeNil())
+		Expect(wc).To(Equal(yevna.WordCount{Lines: 4, Words: 10, Bytes: len(logs)}))
+		Expect(run(ctx, "a b\nc", yevna.WC())).To(Equal("1 3 5"))
+	})
+
+	It("fails on too long lines", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(strings.Repeat("x", 2<<20)),
+			yevna.Grep("x", false),
+			yevna.ToStr(),
+		)
+		Expect(err).To(MatchError(ContainSubstring("too long")))
+	})
+
+	Context("ForEachLine", func() {
+		upper := func(_ int, line string) string { return strings.ToUpper(line) }
+
+		It("transforms each line", func(ctx context.Context) {
+			got := run(ctx, "a\r\nb\nc", yevna.ForEachLine(func(i int, line string) string {
+				return fmt.Sprintf("%d:%s", i, line)
+			}))
+			Expect(got).To(Equal("0:a\n1:b\n2:c\n"))
+
+			got = run(ctx, "a\r\nb\nc", yevna.ForEachLine(upper).KeepLineEndings(true))
+			Expect(got).To(Equal("A\r\nB\nC"))
+		})
+
+		It("filters lines", func(ctx context.Context) {
+			got := run(
+				ctx,
+				"# comment\nkey=value\n\nother=1\n",
+				yevna.FilterLines(func(_ int, line string) (string, bool, error) {
+					return line, line != "" && !strings.HasPrefix(line, "#"), nil
+				}),
+			)
+			Expect(got).To(Equal("key=value\nother=1\n"))
+
+			err := y.Run(
+				ctx,
+				yevna.Input("ok\nbad\n"),
+				yevna.FilterLines(func(_ int, line string) (string, bool, error) {
+					if line == "bad" {
+						return "", false, errors.New("bad line")
+					}
+					return line, true, nil
+				}),
+				yevna.ToStr(),
+			)
+			Expect(err).To(MatchError(ContainSubstring("failed to handle line 1: bad line")))
+		})
+
+		It("limits the line size", func(ctx context.Context) {
+			long := strings.Repeat("x", 100<<10)
+			Expect(
+				run(ctx, long, yevna.ForEachLine(func(_ int, line string) string { return line })),
+			).To(Equal(long + "\n"))
+
+			err := y.Run(
+				ctx,
+				yevna.Input(long),
+				yevna.ForEachLine(func(_ int, line string) string { return line }).
+					MaxTokenSize(1024),
+				yevna.ToStr(),
+			)
+			Expect(err).To(MatchError(ContainSubstring("too long")))
+		})
+
+		It("streams the output of commands", func(ctx context.Context) {
+			start := time.Now()
+			var first time.Duration
+			err := y.Run(
+				ctx,
+				yevna.Exec("sh", "-c", "echo first; sleep 1; echo second"),
+				yevna.ForEachLine(upper),
+				yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+					s := bufio.NewScanner(in.(io.Reader))
+					Expect(s.Scan()).To(BeTrue())
+					Expect(s.Text()).To(Equal("FIRST"))
+					first = time.Since(start)
+					Expect(s.Scan()).To(BeTrue())
+					Expect(s.Text()).To(Equal("SECOND"))
+					return nil, nil
+				}),
+			)
+			Expect(err).To(BeNil())
+			Expect(first).To(BeNumerically("<", 500*time.Millisecond))
+		})
+	})
+})
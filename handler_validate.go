@@ -0,0 +1,309 @@
+package yevna
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-json"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/tlipoca9/yevna/parser"
+)
+
+// Violation is a single violation found by ValidateHandler.
+type Violation struct {
+	// Pointer is the JSON pointer of the invalid value, it is empty for the root value.
+	Pointer string
+	// Message describes the violation.
+	Message string
+}
+
+// ValidationError is the error returned by ValidateHandler, it lists every violation.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("validation failed:")
+	for _, v := range e.Violations {
+		pointer := v.Pointer
+		if pointer == "" {
+			pointer = "(root)"
+		}
+		sb.WriteString("\n  " + pointer + ": " + v.Message)
+	}
+	return sb.String()
+}
+
+// ValidateHandler is a Handler that validates the input.
+//   - raw input (string, []byte or io.Reader) is validated against the JSON Schema.
+//   - decoded values (e.g. from Unmarshal) are validated by the `validate:"..."` struct tags,
+//     and against the JSON Schema if it is set.
+//
+// It returns a *ValidationError listing every violation, or sends the input to next handler.
+type ValidateHandler struct {
+	schema   []byte
+	parser   parser.Parser
+	validate *validator.Validate
+
+	// the schema is compiled once on first use
+	once       sync.Once
+	compiled   *jsonschema.Schema
+	compileErr error
+}
+
+// Validate returns a new ValidateHandler.
+func Validate() *ValidateHandler {
+	return &ValidateHandler{validate: newValidator()}
+}
+
+// newValidator returns a validator which names the fields by their json tags.
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return f.Name
+		}
+		return name
+	})
+	return validate
+}
+
+// WithSchema sets the JSON Schema, draft 2020-12 is used unless the schema declares `$schema`.
+func (h *ValidateHandler) WithSchema(schema []byte) *ValidateHandler {
+	h.schema = schema
+	return h
+}
+
+// WithParser sets the parser of raw input.
+// By default, the format is detected by parser.Detect.
+func (h *ValidateHandler) WithParser(p parser.Parser) *ValidateHandler {
+	h.parser = p
+	return h
+}
+
+// WithValidator sets the validator of struct tags, e.g. to register custom validations.
+// Register a tag name function on it to report the JSON pointers by json tags.
+func (h *ValidateHandler) WithValidator(v *validator.Validate) *ValidateHandler {
+	h.validate = v
+	return h
+}
+
+// Handle implements Handler.
+func (h *ValidateHandler) Handle(c *Context, in any) (any, error) {
+	switch raw := in.(type) {
+	case string, []byte, io.Reader:
+		b, err := readAll(raw)
+		if err != nil {
+			return nil, err
+		}
+		if h.schema == nil {
+			return nil, errors.New("no schema to validate raw input")
+		}
+		v, err := h.decodeRaw(b)
+		if err != nil {
+			return nil, err
+		}
+		violations, err := h.validateSchema(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(violations) != 0 {
+			return nil, &ValidationError{Violations: violations}
+		}
+		if _, ok := raw.(io.Reader); ok {
+			return c.Next(bytes.NewReader(b))
+		}
+		return c.Next(in)
+	}
+
+	var violations []Violation
+	if h.schema != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal input")
+		}
+		v, err := jsonschema.UnmarshalJSON(bytes.NewReader(b))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal input")
+		}
+		violations, err = h.validateSchema(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	vs, err := validateStruct(h.validate, in)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, vs...)
+	if len(violations) != 0 {
+		return nil, &ValidationError{Violations: violations}
+	}
+	return c.Next(in)
+}
+
+// decodeRaw decodes the raw input into the json value expected by jsonschema.
+func (h *ValidateHandler) decodeRaw(b []byte) (any, error) {
+	p := h.parser
+	if p == nil {
+		f, ok := parser.Detect(b)
+		if !ok {
+			return nil, errors.New("failed to detect format")
+		}
+		p = f.Parser
+	}
+
+	var v any
+	if err := p.Unmarshal(b, &v); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal input")
+	}
+	// normalize the value by json, e.g. yaml decodes integers as uint64
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal input")
+	}
+	v, err = jsonschema.UnmarshalJSON(bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal input")
+	}
+	return v, nil
+}
+
+// compileSchema compiles the schema, it is called once by validateSchema.
+func (h *ValidateHandler) compileSchema() (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(h.schema))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse schema")
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	if err := compiler.AddResource("schema.json", doc); err != nil {
+		return nil, errors.Wrap(err, "failed to add schema")
+	}
+	schema, err := compiler.Compile("schema.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compile schema")
+	}
+	return schema, nil
+}
+
+// validateSchema validates v against the schema.
+func (h *ValidateHandler) validateSchema(v any) ([]Violation, error) {
+	h.once.Do(func() {
+		h.compiled, h.compileErr = h.compileSchema()
+	})
+	if h.compileErr != nil {
+		return nil, h.compileErr
+	}
+
+	err := h.compiled.Validate(v)
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return nil, err
+	}
+	var (
+		violations []Violation
+		walk       func(u jsonschema.OutputUnit)
+	)
+	walk = func(u jsonschema.OutputUnit) {
+		if len(u.Errors) == 0 && u.Error != nil {
+			violations = append(
+				violations,
+				Violation{Pointer: u.InstanceLocation, Message: u.Error.String()},
+			)
+		}
+		for _, e := range u.Errors {
+			walk(e)
+		}
+	}
+	walk(*ve.DetailedOutput())
+	return violations, nil
+}
+
+// validateStruct validates the struct tags of v, or of the elements if v is a slice or map.
+func validateStruct(validate *validator.Validate, v any) ([]Violation, error) {
+	var (
+		err      error
+		isStruct bool
+	)
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	switch {
+	case rt == nil:
+		return nil, nil
+	case rt.Kind() == reflect.Struct:
+		isStruct = true
+		err = validate.Struct(v)
+	case rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array || rt.Kind() == reflect.Map:
+		et := rt.Elem()
+		for et.Kind() == reflect.Pointer {
+			et = et.Elem()
+		}
+		if et.Kind() != reflect.Struct {
+			return nil, nil
+		}
+		err = validate.Var(v, "dive")
+	default:
+		return nil, nil
+	}
+
+	var fes validator.ValidationErrors
+	if !errors.As(err, &fes) {
+		return nil, err
+	}
+	violations := make([]Violation, 0, len(fes))
+	for _, fe := range fes {
+		msg := fmt.Sprintf("failed on the %q tag", fe.Tag())
+		if fe.Param() != "" {
+			msg = fmt.Sprintf("failed on the %q tag", fe.Tag()+"="+fe.Param())
+		}
+		violations = append(
+			violations,
+			Violation{Pointer: namespacePointer(fe.Namespace(), isStruct), Message: msg},
+		)
+	}
+	return violations, nil
+}
+
+// namespacePointer converts the namespace of validator, e.g. "Config.servers[0].host",
+// to a JSON pointer, e.g. "/servers/0/host".
+// If hasType is true, the first segment of the namespace is the type name and is dropped.
+func namespacePointer(ns string, hasType bool) string {
+	var sb strings.Builder
+	write := func(token string) {
+		token = strings.ReplaceAll(token, "~", "~0")
+		token = strings.ReplaceAll(token, "/", "~1")
+		sb.WriteString("/" + token)
+	}
+	for i, seg := range strings.Split(ns, ".") {
+		name, index, _ := strings.Cut(seg, "[")
+		if (i != 0 || !hasType) && name != "" {
+			write(name)
+		}
+		if index == "" {
+			continue
+		}
+		for _, idx := range strings.Split(strings.TrimSuffix(index, "]"), "][") {
+			if s, err := strconv.Unquote(idx); err == nil {
+				idx = s
+			}
+			write(idx)
+		}
+	}
+	return sb.String()
+}
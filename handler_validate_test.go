@@ -0,0 +1,143 @@
+package yevna_test
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/tlipoca9/yevna"
+	"github.com/tlipoca9/yevna/parser"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - Validate", func() {
+	y := yevna.New()
+
+	schema := []byte(`{
+  "type": "object",
+  "required": ["name", "servers"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "servers": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["host"],
+        "properties": {"port": {"type": "integer", "maximum": 65535}}
+      }
+    }
+  }
+}`)
+
+	type Server struct {
+		Host string `json:"host" validate:"required"`
+		Port int    `json:"port" validate:"min=1,max=65535"`
+	}
+	type Config struct {
+		Name    string   `json:"name"    validate:"required"`
+		Servers []Server `json:"servers" validate:"required,min=1,dive"`
+	}
+
+	violations := func(err error) []yevna.Violation {
+		var ve *yevna.ValidationError
+		Expect(errors.As(err, &ve)).To(BeTrue())
+		return ve.Violations
+	}
+
+	It("validates raw input against the schema", func(ctx context.Context) {
+		var got map[string]any
+		err := y.Run(
+			ctx,
+			yevna.Input("name: app\nservers:\n  - host: a\n    port: 80\n"),
+			yevna.Validate().WithSchema(schema),
+			yevna.Unmarshal(parser.YAML(), &got),
+		)
+		Expect(err).To(BeNil())
+		Expect(got).To(HaveKeyWithValue("name", "app"))
+	})
+
+	It("lists every violation of the schema", func(ctx context.Context) {
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"name": "", "servers": [{"port": 80}, {"host": "b", "port": 70000}]}`),
+			yevna.Validate().WithSchema(schema),
+		)
+		Expect(err).To(HaveOccurred())
+		pointers := make([]string, 0)
+		for _, v := range violations(err) {
+			pointers = append(pointers, v.Pointer)
+		}
+		Expect(pointers).To(ConsistOf("/name", "/servers/0", "/servers/1/port"))
+		Expect(err.Error()).To(ContainSubstring("/servers/1/port: "))
+	})
+
+	It("validates the struct tags of decoded values", func(ctx context.Context) {
+		var cfg Config
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"servers": [{"host": "a", "port": 80}, {"port": 0}]}`),
+			yevna.Unmarshal(parser.JSON(), &cfg),
+			yevna.Validate(),
+		)
+		Expect(err).To(HaveOccurred())
+		Expect(violations(err)).To(Equal([]yevna.Violation{
+			{Pointer: "/name", Message: `failed on the "required" tag`},
+			{Pointer: "/servers/1/host", Message: `failed on the "required" tag`},
+			{Pointer: "/servers/1/port", Message: `failed on the "min=1" tag`},
+		}))
+	})
+
+	It("validates the elements of decoded slices", func(ctx context.Context) {
+		var servers []Server
+		err := y.Run(
+			ctx,
+			yevna.Input(`[{"host": "a", "port": 80}, {"host": "b", "port": 70000}]`),
+			yevna.Unmarshal(parser.JSON(), &servers),
+			yevna.Validate(),
+		)
+		Expect(err).To(HaveOccurred())
+		Expect(violations(err)).To(Equal([]yevna.Violation{
+			{Pointer: "/1/port", Message: `failed on the "max=65535" tag`},
+		}))
+	})
+
+	It("sends valid values to next handler", func(ctx context.Context) {
+		var cfg Config
+		var out any
+		err := y.Run(
+			ctx,
+			yevna.Input(`{"name": "app", "servers": [{"host": "a", "port": 80}]}`),
+			yevna.Unmarshal(parser.JSON(), &cfg),
+			yevna.Validate().WithSchema(schema),
+			yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+				out = in
+				return in, nil
+			}),
+		)
+		Expect(err).To(BeNil())
+		Expect(out).To(Equal(&cfg))
+	})
+
+	It("reuses the compiled schema across runs", func(ctx context.Context) {
+		validate := yevna.Validate().WithSchema(schema)
+		for range 3 {
+			err := y.Run(ctx, yevna.Input(`{"name": "app", "servers": []}`), validate)
+			Expect(err).To(BeNil())
+			err = y.Run(ctx, yevna.Input(`{"name": ""}`), validate)
+			Expect(violations(err)).To(HaveLen(2))
+		}
+
+		invalid := yevna.Validate().WithSchema([]byte(`{"type": "unknown"}`))
+		for range 2 {
+			err := y.Run(ctx, yevna.Input(`{}`), invalid)
+			Expect(err).To(MatchError(ContainSubstring("failed to compile schema")))
+		}
+	})
+
+	It("fails on raw input without schema", func(ctx context.Context) {
+		err := y.Run(ctx, yevna.Input(`{}`), yevna.Validate())
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,277 @@
+package yevna
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// WalkHandler is a Handler that walks the files under a directory.
+// The paths of files are relative to the working directory if the root is relative.
+// The files are read using Context.FS.
+//
+// By default, it sends the paths as []string in lexical order to next handler.
+// If ForEach is set, each file goes through the sub-chain, and the outputs are sent as []any.
+type WalkHandler struct {
+	root      string
+	filter    func(path string, d fs.DirEntry) bool
+	gitignore bool
+	open      bool
+	missingOK bool
+	each      HandlersChain
+}
+
+// Walk returns a new WalkHandler which walks the files under root.
+// If filter is not nil, only the files for which filter returns true are sent.
+func Walk(root string, filter func(path string, d fs.DirEntry) bool) *WalkHandler {
+	return &WalkHandler{root: root, filter: filter}
+}
+
+// Glob returns a new WalkHandler which walks the files matching pattern.
+// It sends no paths if nothing matches.
+// The pattern syntax is the same as path.Match, and "**" matches zero or more directories,
+// e.g. "deploy/**/*.yaml".
+func Glob(pattern string) *WalkHandler {
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	h := Walk(globRoot(pattern), func(p string, _ fs.DirEntry) bool {
+		return matchGlob(pattern, filepath.ToSlash(p))
+	})
+	h.missingOK = true
+	return h
+}
+
+// Gitignore sets whether to skip the files ignored by the .gitignore files under the root.
+// The .git directory is skipped too.
+func (h *WalkHandler) Gitignore(gitignore bool) *WalkHandler {
+	h.gitignore = gitignore
+	return h
+}
+
+// Open sets whether to send the opened file instead of the path to the ForEach sub-chain.
+// The file is closed after the sub-chain returns.
+func (h *WalkHandler) Open(open bool) *WalkHandler {
+	h.open = open
+	return h
+}
+
+// ForEach sets the sub-chain which handles each file.
+func (h *WalkHandler) ForEach(handlers ...Handler) *WalkHandler {
+	h.each = handlers
+	return h
+}
+
+// Handle implements Handler.
+func (h *WalkHandler) Handle(c *Context, _ any) (any, error) {
+	paths, err := h.walk(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(h.each) == 0 {
+		return paths, nil
+	}
+
+	outs := make([]any, 0, len(paths))
+	for _, p := range paths {
+		if ctx := c.Context(); ctx != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		out, err := h.handle(c, p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to handle %s", p)
+		}
+		outs = append(outs, out)
+	}
+	return outs, nil
+}
+
+// handle sends the file p to the sub-chain.
+// The io.Reader output is read into *bytes.Buffer,
+// as the file and the streams of the sub-chain are closed when it returns.
+func (h *WalkHandler) handle(c *Context, p string) (any, error) {
+	each := append(HandlersChain(h.each).Copy(), HandlerFunc(bufferOutput))
+	if !h.open {
+		return c.fork(each).Next(p)
+	}
+
+	f, err := c.FS().OpenFile(c.path(p), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open file")
+	}
+	defer f.Close()
+	return c.fork(each).Next(f)
+}
+
+// walk returns the paths of the files under the root.
+func (h *WalkHandler) walk(c *Context) ([]string, error) {
+	fsys := c.FS()
+	root := c.path(h.root)
+
+	var (
+		paths []string
+		rules []ignoreRule
+	)
+	err := fs.WalkDir(fsys, root, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if full == root && h.missingOK && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(root, full)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if h.gitignore && rel != "." {
+			if d.IsDir() && d.Name() == ".git" || ignored(rules, rel, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.IsDir() {
+			if h.gitignore {
+				rs, err := readGitignore(fsys, full, rel)
+				if err != nil {
+					return err
+				}
+				rules = append(rules, rs...)
+			}
+			return nil
+		}
+
+		p := filepath.Join(h.root, filepath.FromSlash(rel))
+		if h.filter == nil || h.filter(p, d) {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to walk")
+	}
+	return paths, nil
+}
+
+// globRoot returns the leading directories of pattern without meta characters.
+func globRoot(pattern string) string {
+	segs := strings.Split(pattern, "/")
+	i := 0
+	for i < len(segs)-1 && !strings.ContainsAny(segs[i], `*?[\`) {
+		i++
+	}
+	root := strings.Join(segs[:i], "/")
+	if root == "" && strings.HasPrefix(pattern, "/") {
+		return "/"
+	}
+	if root == "" {
+		return "."
+	}
+	return filepath.FromSlash(root)
+}
+
+// matchGlob reports whether name matches the slash-separated pattern,
+// "**" matches zero or more segments.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ignoreRule is a pattern of a .gitignore file.
+type ignoreRule struct {
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// match reports whether the rule matches rel, which is relative to the walk root.
+func (r ignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "." {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	if r.anchored {
+		return matchGlob(r.pattern, rel)
+	}
+	return matchGlob(r.pattern, path.Base(rel))
+}
+
+// ignored reports whether rel is ignored by the rules, the last matching rule wins.
+func ignored(rules []ignoreRule, rel string, isDir bool) bool {
+	ret := false
+	for _, r := range rules {
+		if r.match(rel, isDir) {
+			ret = !r.negate
+		}
+	}
+	return ret
+}
+
+// readGitignore reads the rules of the .gitignore file in dir,
+// rel is the dir relative to the walk root.
+func readGitignore(fsys FS, dir, rel string) ([]ignoreRule, error) {
+	b, err := fs.ReadFile(fsys, path.Join(dir, ".gitignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{base: rel}
+		if strings.HasPrefix(line, "!") {
+			r.negate, line = true, line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly, line = true, strings.TrimSuffix(line, "/")
+		}
+		r.anchored = strings.Contains(line, "/")
+		r.pattern = strings.TrimPrefix(line, "/")
+		if r.pattern != "" {
+			rules = append(rules, r)
+		}
+	}
+	return rules, scanner.Err()
+}
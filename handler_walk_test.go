@@ -0,0 +1,116 @@
+package yevna_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tlipoca9/yevna"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler - Walk", func() {
+	var (
+		y   = yevna.New()
+		dir string
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		for name, content := range map[string]string{
+			".gitignore":              "*.log\n/build/\n!keep.log\n",
+			"app.yaml":                "app",
+			"deploy/base.yaml":        "base",
+			"deploy/prod/values.yaml": "prod",
+			"deploy/prod/values.json": "{}",
+			"deploy/debug.log":        "debug",
+			"deploy/keep.log":         "keep",
+			"deploy/.gitignore":       "prod/*.json\n",
+			"build/out.yaml":          "out",
+			".git/HEAD":               "ref",
+		} {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		}
+	})
+
+	run := func(ctx context.Context, h yevna.Handler) any {
+		var got any
+		err := y.Run(
+			ctx,
+			yevna.Chdir(dir),
+			h,
+			yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+				got = in
+				return in, nil
+			}),
+		)
+		Expect(err).To(BeNil())
+		return got
+	}
+
+	It("globs with **", func(ctx context.Context) {
+		Expect(run(ctx, yevna.Glob("deploy/**/*.yaml"))).To(Equal([]string{
+			"deploy/base.yaml",
+			"deploy/prod/values.yaml",
+		}))
+		Expect(run(ctx, yevna.Glob("*.yaml"))).To(Equal([]string{"app.yaml"}))
+		Expect(run(ctx, yevna.Glob("**/*.yaml"))).To(Equal([]string{
+			"app.yaml",
+			"build/out.yaml",
+			"deploy/base.yaml",
+			"deploy/prod/values.yaml",
+		}))
+		Expect(run(ctx, yevna.Glob("missing/*.yaml"))).To(BeEmpty())
+	})
+
+	It("respects .gitignore", func(ctx context.Context) {
+		Expect(run(ctx, yevna.Walk(".", nil).Gitignore(true))).To(Equal([]string{
+			".gitignore",
+			"app.yaml",
+			"deploy/.gitignore",
+			"deploy/base.yaml",
+			"deploy/keep.log",
+			"deploy/prod/values.yaml",
+		}))
+	})
+
+	It("walks with the filter", func(ctx context.Context) {
+		got := run(ctx, yevna.Walk("deploy", func(path string, d fs.DirEntry) bool {
+			return strings.HasSuffix(d.Name(), ".log")
+		}))
+		Expect(got).To(Equal([]string{"deploy/debug.log", "deploy/keep.log"}))
+	})
+
+	It("fans out each file to the sub-chain", func(ctx context.Context) {
+		got := run(ctx, yevna.Glob("deploy/**/*.yaml").Open(true).ForEach(
+			yevna.HandlerFunc(func(_ *yevna.Context, in any) (any, error) {
+				f := in.(*os.File)
+				b, err := io.ReadAll(f)
+				return filepath.Base(f.Name()) + "=" + string(b), err
+			}),
+		))
+		Expect(got).To(Equal([]any{"base.yaml=base", "values.yaml=prod"}))
+
+		got = run(ctx, yevna.Glob("*.yaml").ForEach(yevna.ToStr()))
+		Expect(got).To(Equal([]any{"app.yaml"}))
+
+		// the streams of the sub-chain are read before the files are closed
+		got = run(ctx, yevna.Glob("deploy/**/*.yaml").Open(true).ForEach(yevna.Grep("prod", false)))
+		Expect(got).To(HaveLen(2))
+		Expect(got.([]any)[1]).To(BeAssignableToTypeOf(&bytes.Buffer{}))
+		Expect(got.([]any)[1].(*bytes.Buffer).String()).To(Equal("prod\n"))
+	})
+
+	It("fails if the root of Walk doesn't exist", func(ctx context.Context) {
+		err := y.Run(ctx, yevna.Chdir(dir), yevna.Walk("missing", nil))
+		Expect(err).To(HaveOccurred())
+	})
+})
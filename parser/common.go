@@ -20,3 +20,21 @@ func YAML() Parser {
 func TOML() Parser {
 	return Func(toml.Unmarshal)
 }
+
+// DuplicateKey defines how to handle duplicate keys, e.g. the keys in a section of INIParser
+// or the header columns of CsvParser.
+type DuplicateKey int
+
+const (
+	// DuplicateKeyLast keeps the last value.
+	DuplicateKeyLast DuplicateKey = iota
+	// DuplicateKeyFirst keeps the first value.
+	DuplicateKeyFirst
+	// DuplicateKeyError returns an error.
+	DuplicateKeyError
+	// DuplicateKeyAppend collects all values into a []string.
+	DuplicateKeyAppend
+	// DuplicateKeyRename renames the duplicate keys by appending _2, _3, etc.
+	// It is only supported by the header columns of CsvParser.
+	DuplicateKeyRename
+)
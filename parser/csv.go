@@ -5,33 +5,64 @@ import (
 	"encoding/csv"
 	"io"
 	"slices"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/cockroachdb/errors"
 	"github.com/go-viper/mapstructure/v2"
 )
 
 type CsvParser struct {
-	conf    *mapstructure.DecoderConfig
-	headers []string
+	conf             *mapstructure.DecoderConfig
+	headers          []string
+	comma            rune
+	comment          rune
+	lazyQuotes       bool
+	trimLeadingSpace bool
+	normalizers      []func(string) string
+	duplicate        DuplicateKey
 }
 
 func (p *CsvParser) WithDecoderConfig(conf *mapstructure.DecoderConfig) *CsvParser {
-	p.conf = conf
-	return p
+	return with(p, func(c *CsvParser) { c.conf = copyConfig(conf) })
 }
 
 func (p *CsvParser) WithHeaders(headers ...string) *CsvParser {
-	p.headers = headers
-	return p
+	return with(p, func(c *CsvParser) { c.headers = slices.Clone(headers) })
 }
 
-func (p *CsvParser) lazyInit() {
-	if p.conf == nil {
-		p.conf = &mapstructure.DecoderConfig{TagName: "json"}
-	}
-	if p.conf.TagName == "" {
-		p.conf.TagName = "json"
-	}
+// WithComma sets the field delimiter, the default is ','.
+func (p *CsvParser) WithComma(r rune) *CsvParser {
+	return with(p, func(c *CsvParser) { c.comma = r })
+}
+
+// WithComment sets the comment character, lines beginning with it are ignored.
+func (p *CsvParser) WithComment(r rune) *CsvParser {
+	return with(p, func(c *CsvParser) { c.comment = r })
+}
+
+// WithLazyQuotes allows quotes to appear in unquoted fields,
+// and non-doubled quotes to appear in quoted fields.
+func (p *CsvParser) WithLazyQuotes(b bool) *CsvParser {
+	return with(p, func(c *CsvParser) { c.lazyQuotes = b })
+}
+
+// WithTrimLeadingSpace ignores the leading white spaces of fields.
+func (p *CsvParser) WithTrimLeadingSpace(b bool) *CsvParser {
+	return with(p, func(c *CsvParser) { c.trimLeadingSpace = b })
+}
+
+// WithHeaderNormalizer sets the functions applied to each header in order,
+// e.g. WithHeaderNormalizer(strings.TrimSpace, SnakeCase).
+func (p *CsvParser) WithHeaderNormalizer(f ...func(string) string) *CsvParser {
+	return with(p, func(c *CsvParser) { c.normalizers = slices.Clone(f) })
+}
+
+// WithDuplicateHeader sets how to handle duplicate headers.
+// The default is DuplicateKeyLast.
+func (p *CsvParser) WithDuplicateHeader(d DuplicateKey) *CsvParser {
+	return with(p, func(c *CsvParser) { c.duplicate = d })
 }
 
 // CSV returns a new CsvParser
@@ -39,48 +70,167 @@ func CSV() *CsvParser {
 	return &CsvParser{}
 }
 
-func (p *CsvParser) Unmarshal(b []byte, v any) error {
-	p.lazyInit()
+// TSV is the shortcut for CSV().WithComma('\t')
+func TSV() *CsvParser {
+	return CSV().WithComma('\t')
+}
 
-	p.conf.Result = v
-	dec, err := mapstructure.NewDecoder(p.conf)
+func (p *CsvParser) Unmarshal(b []byte, v any) error {
+	dec, err := newDecoder(p.conf, "json", v)
 	if err != nil {
 		return errors.Wrapf(err, "create decoder failed")
 	}
 
+	var results []any
+	err = p.read(bytes.NewReader(b), func(record map[string]any) error {
+		results = append(results, record)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = dec.Decode(results)
+	if err != nil {
+		return errors.Wrapf(err, "decode failed")
+	}
+
+	return nil
+}
+
+// Stream implements StreamParser.
+// It reads the records from r one by one, so the input doesn't need to fit in memory.
+func (p *CsvParser) Stream(r io.Reader, fn func(decode func(v any) error) error) error {
+	return p.read(r, func(record map[string]any) error {
+		return fn(func(v any) error {
+			dec, err := newDecoder(p.conf, "json", v)
+			if err != nil {
+				return errors.Wrapf(err, "create decoder failed")
+			}
+			err = dec.Decode(record)
+			if err != nil {
+				return errors.Wrapf(err, "decode failed")
+			}
+			return nil
+		})
+	})
+}
+
+// read reads the records from r and calls fn for each record.
+func (p *CsvParser) read(r io.Reader, fn func(record map[string]any) error) error {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	if p.comma != 0 {
+		cr.Comma = p.comma
+	}
+	cr.Comment = p.comment
+	cr.LazyQuotes = p.lazyQuotes
+	cr.TrimLeadingSpace = p.trimLeadingSpace
+
 	var (
-		results []any
+		err     error
 		record  []string
+		headers = p.headers
 	)
-	r := csv.NewReader(bytes.NewBuffer(b))
-	r.ReuseRecord = true
-	if len(p.headers) != 0 {
-		r.FieldsPerRecord = len(p.headers)
+	if len(headers) != 0 {
+		cr.FieldsPerRecord = len(headers)
 	} else {
-		record, err = r.Read()
+		record, err = cr.Read()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return nil
 			}
 			return errors.Wrapf(err, "read header failed")
 		}
-		p.headers = slices.Clone(record)
+		headers = slices.Clone(record)
+	}
+	headers = slices.Clone(headers)
+	for i := range headers {
+		for _, f := range p.normalizers {
+			headers[i] = f(headers[i])
+		}
 	}
-	for record, err = r.Read(); err == nil; record, err = r.Read() {
-		m := make(map[string]string)
-		for i, key := range p.headers {
-			m[key] = record[i]
+	if p.duplicate == DuplicateKeyRename {
+		headers = renameDuplicates(headers)
+	} else if p.duplicate == DuplicateKeyError {
+		for i, h := range headers {
+			if slices.Contains(headers[:i], h) {
+				return errors.Newf("duplicate header %q", h)
+			}
+		}
+	}
+
+	for record, err = cr.Read(); err == nil; record, err = cr.Read() {
+		m := make(map[string]any, len(headers))
+		for i, key := range headers {
+			exist, ok := m[key]
+			switch {
+			case !ok, p.duplicate == DuplicateKeyLast:
+				m[key] = record[i]
+			case p.duplicate == DuplicateKeyAppend:
+				if ss, ok := exist.([]string); ok {
+					m[key] = append(ss, record[i])
+				} else {
+					m[key] = []string{exist.(string), record[i]}
+				}
+			}
+		}
+		if err := fn(m); err != nil {
+			return err
 		}
-		results = append(results, m)
 	}
 	if !errors.Is(err, io.EOF) {
 		return errors.Wrapf(err, "read record failed")
 	}
+	return nil
+}
 
-	err = dec.Decode(results)
-	if err != nil {
-		return errors.Wrapf(err, "decode failed")
+// renameDuplicates renames the duplicate keys by appending _2, _3, etc.
+func renameDuplicates(keys []string) []string {
+	seen := make(map[string]int, len(keys))
+	for _, k := range keys {
+		seen[k] = 0
+	}
+	ret := make([]string, 0, len(keys))
+	for _, k := range keys {
+		n := seen[k] + 1
+		seen[k] = n
+		if n == 1 {
+			ret = append(ret, k)
+			continue
+		}
+		name := k + "_" + strconv.Itoa(n)
+		for _, exist := seen[name]; exist; _, exist = seen[name] {
+			n++
+			name = k + "_" + strconv.Itoa(n)
+		}
+		seen[k] = n
+		seen[name] = 1
+		ret = append(ret, name)
 	}
+	return ret
+}
 
-	return nil
+// SnakeCase converts s to snake_case, e.g. "Order ID" to "order_id", "createdAt" to "created_at".
+// It can be used as a header normalizer.
+func SnakeCase(s string) string {
+	var sb strings.Builder
+	rs := []rune(strings.TrimSpace(s))
+	for i, r := range rs {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 && (unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1]) ||
+				(i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(rs[i-1]))) {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "_") {
+				sb.WriteByte('_')
+			}
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "_")
 }
@@ -1,6 +1,10 @@
 package parser_test
 
 import (
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+
 	"github.com/tlipoca9/yevna/parser"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -78,4 +82,119 @@ qwe,asd
 			}))
 		})
 	})
+
+	When("input is semicolon-delimited with comments", func() {
+		It("return expected object", func() {
+			p := parser.CSV().WithComma(';').WithComment('#').WithTrimLeadingSpace(true)
+			err := p.Unmarshal([]byte(`
+# exported by finance
+FOO; BAR
+42;  "4,242"
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]string{
+				{"FOO": "42", "BAR": "4,242"},
+			}))
+		})
+	})
+
+	When("input has bare quotes", func() {
+		It("return error without lazy quotes", func() {
+			err := parser.CSV().Unmarshal([]byte("FOO\nsay \"hi\"\n"), &got)
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("return expected object with lazy quotes", func() {
+			err := parser.CSV().WithLazyQuotes(true).Unmarshal([]byte("FOO\nsay \"hi\"\n"), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]string{{"FOO": `say "hi"`}}))
+		})
+	})
+
+	When("input is tsv", func() {
+		It("return expected object", func() {
+			err := parser.TSV().Unmarshal([]byte("FOO\tBAR\n4,2\t42\n"), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]string{{"FOO": "4,2", "BAR": "42"}}))
+		})
+	})
+
+	When("headers are normalized", func() {
+		It("return expected object", func() {
+			p := parser.CSV().WithHeaderNormalizer(strings.TrimSpace, strings.ToLower)
+			err := p.Unmarshal([]byte(" Foo , BAR\n1,2\n"), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]string{{"foo": "1", "bar": "2"}}))
+
+			got = nil
+			p = parser.CSV().WithHeaderNormalizer(parser.SnakeCase)
+			err = p.Unmarshal([]byte("Order ID,createdAt,HTTPStatus,Amount (EUR)\n1,2,3,4\n"), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]string{
+				{"order_id": "1", "created_at": "2", "http_status": "3", "amount_eur": "4"},
+			}))
+		})
+	})
+
+	When("headers are duplicate", func() {
+		input := []byte("FOO,BAR,FOO\n1,2,3\n")
+
+		It("keep the last value by default", func() {
+			err := parser.CSV().Unmarshal(input, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]string{{"FOO": "3", "BAR": "2"}}))
+		})
+
+		It("keep the first value", func() {
+			err := parser.CSV().WithDuplicateHeader(parser.DuplicateKeyFirst).Unmarshal(input, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]string{{"FOO": "1", "BAR": "2"}}))
+		})
+
+		It("rename the duplicates", func() {
+			err := parser.CSV().
+				WithDuplicateHeader(parser.DuplicateKeyRename).
+				Unmarshal(input, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]string{{"FOO": "1", "BAR": "2", "FOO_2": "3"}}))
+		})
+
+		It("collect all values", func() {
+			var got []map[string]any
+			err := parser.CSV().
+				WithDuplicateHeader(parser.DuplicateKeyAppend).
+				Unmarshal(input, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{{"FOO": []string{"1", "3"}, "BAR": "2"}}))
+		})
+
+		It("return error", func() {
+			err := parser.CSV().WithDuplicateHeader(parser.DuplicateKeyError).Unmarshal(input, &got)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Context("Stream", func() {
+		It("decode record by record", func() {
+			type record struct {
+				Foo string `json:"FOO"`
+				Bar int    `json:"BAR"`
+			}
+			var records []record
+			p := parser.CSV().WithDecoderConfig(&mapstructure.DecoderConfig{WeaklyTypedInput: true})
+			err := p.Stream(
+				strings.NewReader("FOO,BAR\na,1\nb,2\n"),
+				func(decode func(v any) error) error {
+					var r record
+					if err := decode(&r); err != nil {
+						return err
+					}
+					records = append(records, r)
+					return nil
+				},
+			)
+			Expect(err).To(BeNil())
+			Expect(records).To(Equal([]record{{"a", 1}, {"b", 2}}))
+		})
+	})
 })
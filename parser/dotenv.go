@@ -11,17 +11,7 @@ type DotenvParser struct {
 }
 
 func (p *DotenvParser) WithDecoderConfig(conf *mapstructure.DecoderConfig) *DotenvParser {
-	p.conf = conf
-	return p
-}
-
-func (p *DotenvParser) lazyInit() {
-	if p.conf == nil {
-		p.conf = &mapstructure.DecoderConfig{TagName: "dotenv"}
-	}
-	if p.conf.TagName == "" {
-		p.conf.TagName = "dotenv"
-	}
+	return with(p, func(c *DotenvParser) { c.conf = copyConfig(conf) })
 }
 
 // Dotenv returns a new DotenvParser
@@ -30,10 +20,7 @@ func Dotenv() *DotenvParser {
 }
 
 func (p *DotenvParser) Unmarshal(b []byte, v any) error {
-	p.lazyInit()
-
-	p.conf.Result = v
-	dec, err := mapstructure.NewDecoder(p.conf)
+	dec, err := newDecoder(p.conf, "dotenv", v)
 	if err != nil {
 		return errors.Wrapf(err, "create decoder failed")
 	}
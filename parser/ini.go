@@ -0,0 +1,320 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// INIFile is the document of an ini file.
+// It keeps the order of sections and keys, and the comments,
+// so that decoding into INIFile and encoding it back preserves them.
+type INIFile struct {
+	// Sections of the file, the keys before the first section header
+	// belong to a section with empty name.
+	Sections []*INISection
+	// Comments after the last key.
+	Comments []string
+}
+
+// Section returns the first section with the name, or nil if not found.
+func (f *INIFile) Section(name string) *INISection {
+	for _, s := range f.Sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// INISection is a section of INIFile.
+type INISection struct {
+	Name string
+	Keys []*INIKey
+	// Comments and blank lines before the section header, including the comment markers.
+	Comments []string
+}
+
+// Key returns the first key with the name, or nil if not found.
+func (s *INISection) Key(name string) *INIKey {
+	for _, k := range s.Keys {
+		if k.Name == name {
+			return k
+		}
+	}
+	return nil
+}
+
+// INIKey is a key of INISection.
+type INIKey struct {
+	Name  string
+	Value string
+	// Comments and blank lines before the key, including the comment markers.
+	Comments []string
+}
+
+// INIParser parses and encodes ini files.
+//   - sections are decoded as nested maps,
+//     the keys before the first section are decoded at the top level.
+//   - lines starting with ';' or '#' are comments.
+//   - keys and values are separated by '=' or ':'.
+//   - a line ending with '\' is continued on the next line, except comments.
+//   - keys and values are trimmed, values are kept verbatim otherwise.
+//
+// Decoding into *INIFile keeps the comments.
+type INIParser struct {
+	conf      *mapstructure.DecoderConfig
+	duplicate DuplicateKey
+}
+
+func (p *INIParser) WithDecoderConfig(conf *mapstructure.DecoderConfig) *INIParser {
+	return with(p, func(c *INIParser) { c.conf = copyConfig(conf) })
+}
+
+// WithDuplicateKey sets how to handle duplicate keys in a section.
+// The default is DuplicateKeyLast.
+func (p *INIParser) WithDuplicateKey(d DuplicateKey) *INIParser {
+	return with(p, func(c *INIParser) { c.duplicate = d })
+}
+
+// INI returns a new INIParser
+func INI() *INIParser {
+	return &INIParser{}
+}
+
+func (p *INIParser) Unmarshal(b []byte, v any) error {
+	f, err := p.parse(b)
+	if err != nil {
+		return errors.Wrapf(err, "unmarshal failed")
+	}
+	if ff, ok := v.(*INIFile); ok {
+		*ff = *f
+		return nil
+	}
+
+	raw := make(map[string]any)
+	for _, s := range f.Sections {
+		m := raw
+		if s.Name != "" {
+			exist, ok := raw[s.Name]
+			sm, isSection := exist.(map[string]any)
+			if ok && !isSection {
+				return errors.Newf(
+					"section %q clashes with the key before the first section",
+					s.Name,
+				)
+			}
+			if !ok {
+				sm = make(map[string]any)
+				raw[s.Name] = sm
+			}
+			m = sm
+		}
+		for _, k := range s.Keys {
+			exist, ok := m[k.Name]
+			if !ok {
+				m[k.Name] = k.Value
+				continue
+			}
+			if _, isSection := exist.(map[string]any); isSection {
+				return errors.Newf("key %q clashes with the section", k.Name)
+			}
+			switch p.duplicate {
+			case DuplicateKeyLast:
+				m[k.Name] = k.Value
+			case DuplicateKeyFirst:
+			case DuplicateKeyError:
+				return errors.Newf("duplicate key %q in section %q", k.Name, s.Name)
+			case DuplicateKeyAppend:
+				switch e := exist.(type) {
+				case []string:
+					m[k.Name] = append(e, k.Value)
+				case string:
+					m[k.Name] = []string{e, k.Value}
+				}
+			}
+		}
+	}
+
+	dec, err := newDecoder(p.conf, "ini", v)
+	if err != nil {
+		return errors.Wrapf(err, "create decoder failed")
+	}
+	err = dec.Decode(raw)
+	if err != nil {
+		return errors.Wrapf(err, "decode failed")
+	}
+	return nil
+}
+
+// parse parses b into INIFile.
+func (p *INIParser) parse(b []byte) (*INIFile, error) {
+	f := &INIFile{}
+	section := &INISection{}
+	f.Sections = append(f.Sections, section)
+
+	var comments []string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		// comments are never continued, e.g. "; install to C:\"
+		isComment := strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#")
+		for !isComment && strings.HasSuffix(line, `\`) && scanner.Scan() {
+			lineno++
+			line = strings.TrimSuffix(line, `\`) + strings.TrimSpace(scanner.Text())
+		}
+
+		switch {
+		case line == "" || line[0] == ';' || line[0] == '#':
+			comments = append(comments, line)
+		case line[0] == '[':
+			if line[len(line)-1] != ']' {
+				return nil, errors.Newf("line %d: invalid section header %q", lineno, line)
+			}
+			section = &INISection{
+				Name:     strings.TrimSpace(line[1 : len(line)-1]),
+				Comments: comments,
+			}
+			f.Sections = append(f.Sections, section)
+			comments = nil
+		default:
+			i := strings.IndexAny(line, "=:")
+			if i < 0 {
+				return nil, errors.Newf("line %d: missing separator in %q", lineno, line)
+			}
+			section.Keys = append(section.Keys, &INIKey{
+				Name:     strings.TrimSpace(line[:i]),
+				Value:    strings.TrimSpace(line[i+1:]),
+				Comments: comments,
+			})
+			comments = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	f.Comments = comments
+
+	if len(f.Sections[0].Keys) == 0 && len(f.Sections) > 1 {
+		f.Sections = f.Sections[1:]
+	}
+	return f, nil
+}
+
+// Marshal encodes v as ini.
+// If v is INIFile or *INIFile, the order and comments are preserved.
+// Otherwise, v is decoded into map[string]any using mapstructure,
+// top level values are written before the sections, maps are written as sections,
+// slices are written as duplicate keys, and keys are written in lexical order.
+func (p *INIParser) Marshal(v any) ([]byte, error) {
+	switch f := v.(type) {
+	case *INIFile:
+		return f.bytes(), nil
+	case INIFile:
+		return f.bytes(), nil
+	}
+
+	m, err := toMap(v, tagName(p.conf, "ini"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshal failed")
+	}
+
+	f := &INIFile{}
+	root := &INISection{}
+	f.Sections = append(f.Sections, root)
+	for _, name := range sortedKeys(m) {
+		sm, ok := m[name].(map[string]any)
+		if !ok {
+			root.Keys = append(root.Keys, iniKeys(name, m[name])...)
+			continue
+		}
+		section := &INISection{Name: name}
+		for _, k := range sortedKeys(sm) {
+			section.Keys = append(section.Keys, iniKeys(k, sm[k])...)
+		}
+		f.Sections = append(f.Sections, section)
+	}
+	if len(root.Keys) == 0 {
+		f.Sections = f.Sections[1:]
+	}
+	return f.bytes(), nil
+}
+
+// iniKeys returns the keys of the value, a slice is written as duplicate keys.
+func iniKeys(name string, v any) []*INIKey {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return []*INIKey{{Name: name, Value: toStr(v)}}
+	}
+	keys := make([]*INIKey, 0, rv.Len())
+	for i := range rv.Len() {
+		keys = append(keys, &INIKey{Name: name, Value: toStr(rv.Index(i).Interface())})
+	}
+	return keys
+}
+
+func (f *INIFile) bytes() []byte {
+	var buf bytes.Buffer
+	for _, s := range f.Sections {
+		for _, c := range s.Comments {
+			buf.WriteString(c)
+			buf.WriteByte('\n')
+		}
+		if s.Name != "" {
+			if buf.Len() > 0 && len(s.Comments) == 0 {
+				buf.WriteByte('\n')
+			}
+			buf.WriteString("[" + s.Name + "]\n")
+		}
+		for _, k := range s.Keys {
+			for _, c := range k.Comments {
+				buf.WriteString(c)
+				buf.WriteByte('\n')
+			}
+			buf.WriteString(k.Name + " = " + k.Value + "\n")
+		}
+	}
+	for _, c := range f.Comments {
+		buf.WriteString(c)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// toMap decodes v into map[string]any using mapstructure.
+func toMap(v any, tagName string) (map[string]any, error) {
+	if m, ok := v.(map[string]any); ok {
+		return m, nil
+	}
+	var m map[string]any
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{TagName: tagName, Result: &m})
+	if err != nil {
+		return nil, err
+	}
+	err = dec.Decode(v)
+	return m, err
+}
+
+// sortedKeys returns the keys of m in lexical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// toStr converts v to string.
+func toStr(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
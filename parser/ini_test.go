@@ -0,0 +1,175 @@
+package parser_test
+
+import (
+	"github.com/go-viper/mapstructure/v2"
+
+	"github.com/tlipoca9/yevna/parser"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("INIParser", func() {
+	input := []byte(`
+; global settings
+name = demo
+
+# database settings
+[database]
+host = localhost
+port: 5432
+; comment before key
+query = SELECT * \
+        FROM users
+
+[servers]
+ip = 10.0.0.1
+ip = 10.0.0.2
+`[1:])
+
+	When("input is empty", func() {
+		It("return empty object", func() {
+			var got map[string]any
+			err := parser.INI().Unmarshal([]byte(""), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(BeEmpty())
+		})
+	})
+
+	When("input has sections", func() {
+		It("return expected object", func() {
+			var got map[string]any
+			err := parser.INI().Unmarshal(input, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(map[string]any{
+				"name": "demo",
+				"database": map[string]any{
+					"host":  "localhost",
+					"port":  "5432",
+					"query": "SELECT * FROM users",
+				},
+				"servers": map[string]any{"ip": "10.0.0.2"},
+			}))
+		})
+	})
+
+	When("decode into struct", func() {
+		It("return expected object", func() {
+			type config struct {
+				Name     string `ini:"name"`
+				Database struct {
+					Host string `ini:"host"`
+					Port int    `ini:"port"`
+				} `ini:"database"`
+			}
+			var got config
+			err := parser.INI().WithDecoderConfig(&mapstructure.DecoderConfig{
+				TagName:          "ini",
+				WeaklyTypedInput: true,
+			}).Unmarshal(input, &got)
+			Expect(err).To(BeNil())
+			Expect(got.Name).To(Equal("demo"))
+			Expect(got.Database.Host).To(Equal("localhost"))
+			Expect(got.Database.Port).To(Equal(5432))
+		})
+	})
+
+	When("input has duplicate keys", func() {
+		It("keep the first value", func() {
+			var got map[string]any
+			err := parser.INI().WithDuplicateKey(parser.DuplicateKeyFirst).Unmarshal(input, &got)
+			Expect(err).To(BeNil())
+			Expect(got["servers"]).To(Equal(map[string]any{"ip": "10.0.0.1"}))
+		})
+
+		It("collect all values", func() {
+			var got map[string]any
+			err := parser.INI().WithDuplicateKey(parser.DuplicateKeyAppend).Unmarshal(input, &got)
+			Expect(err).To(BeNil())
+			Expect(got["servers"]).To(Equal(map[string]any{"ip": []string{"10.0.0.1", "10.0.0.2"}}))
+		})
+
+		It("return error", func() {
+			var got map[string]any
+			err := parser.INI().WithDuplicateKey(parser.DuplicateKeyError).Unmarshal(input, &got)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	When("comment ends with backslash", func() {
+		It("doesn't continue the comment", func() {
+			var got map[string]any
+			err := parser.INI().Unmarshal([]byte("; install to C:\\\nkey = v\n"), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(map[string]any{"key": "v"}))
+		})
+	})
+
+	When("input is invalid", func() {
+		It("return error", func() {
+			var got map[string]any
+			err := parser.INI().Unmarshal([]byte("[section\n"), &got)
+			Expect(err).NotTo(BeNil())
+			err = parser.INI().Unmarshal([]byte("key\n"), &got)
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("return error if a key clashes with a section", func() {
+			var got map[string]any
+			err := parser.INI().
+				WithDuplicateKey(parser.DuplicateKeyAppend).
+				Unmarshal([]byte("[a]\nx = 1\n[ ]\na = 2\n"), &got)
+			Expect(err).To(MatchError(ContainSubstring(`key "a" clashes with the section`)))
+			err = parser.INI().Unmarshal([]byte("a = 1\n[a]\nx = 2\n"), &got)
+			Expect(err).To(MatchError(ContainSubstring(`section "a" clashes`)))
+		})
+	})
+
+	Context("Marshal", func() {
+		It("preserve comments on round trip", func() {
+			p := parser.INI()
+			var f parser.INIFile
+			err := p.Unmarshal(input, &f)
+			Expect(err).To(BeNil())
+			f.Section("database").Key("host").Value = "db.internal"
+
+			b, err := p.Marshal(&f)
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal(`
+; global settings
+name = demo
+
+# database settings
+[database]
+host = db.internal
+port = 5432
+; comment before key
+query = SELECT * FROM users
+
+[servers]
+ip = 10.0.0.1
+ip = 10.0.0.2
+`[1:]))
+		})
+
+		It("encode map", func() {
+			b, err := parser.INI().Marshal(map[string]any{
+				"name":     "demo",
+				"database": map[string]any{"port": 5432, "host": "localhost"},
+				"servers":  map[string]any{"ip": []string{"10.0.0.1", "10.0.0.2"}},
+			})
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal(`
+name = demo
+
+[database]
+host = localhost
+port = 5432
+
+[servers]
+ip = 10.0.0.1
+ip = 10.0.0.2
+`[1:]))
+		})
+	})
+})
@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// LogfmtParser parses logfmt lines such as `level=info msg="hello world" dur=12ms`,
+// which are emitted by slog.TextHandler and many other loggers.
+//   - each non-empty line is a record decoded as map[string]string.
+//   - quoted values are unquoted using strconv.Unquote.
+//   - keys without value are decoded as "true".
+//   - the last value of duplicate keys wins.
+type LogfmtParser struct {
+	conf *mapstructure.DecoderConfig
+}
+
+func (p *LogfmtParser) WithDecoderConfig(conf *mapstructure.DecoderConfig) *LogfmtParser {
+	return with(p, func(c *LogfmtParser) { c.conf = copyConfig(conf) })
+}
+
+// Logfmt returns a new LogfmtParser
+func Logfmt() *LogfmtParser {
+	return &LogfmtParser{}
+}
+
+func (p *LogfmtParser) Unmarshal(b []byte, v any) error {
+	dec, err := newDecoder(p.conf, "logfmt", v)
+	if err != nil {
+		return errors.Wrapf(err, "create decoder failed")
+	}
+
+	var records []map[string]string
+	err = readLogfmt(bytes.NewReader(b), func(record map[string]string) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = dec.Decode(records)
+	if err != nil {
+		return errors.Wrapf(err, "decode failed")
+	}
+	return nil
+}
+
+// Stream implements StreamParser.
+// It reads the lines from r as they are written, so it works with endless streams.
+func (p *LogfmtParser) Stream(r io.Reader, fn func(decode func(v any) error) error) error {
+	return readLogfmt(r, func(record map[string]string) error {
+		return fn(func(v any) error {
+			dec, err := newDecoder(p.conf, "logfmt", v)
+			if err != nil {
+				return errors.Wrapf(err, "create decoder failed")
+			}
+			err = dec.Decode(record)
+			if err != nil {
+				return errors.Wrapf(err, "decode failed")
+			}
+			return nil
+		})
+	})
+}
+
+// readLogfmt reads the records from r and calls fn for each record.
+func readLogfmt(r io.Reader, fn func(record map[string]string) error) error {
+	br := bufio.NewReader(r)
+	for lineno := 1; ; lineno++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return errors.Wrapf(err, "read failed")
+		}
+		if len(bytes.TrimSpace(line)) != 0 {
+			record, perr := parseLogfmtLine(line)
+			if perr != nil {
+				return errors.Wrapf(perr, "line %d", lineno)
+			}
+			if ferr := fn(record); ferr != nil {
+				return ferr
+			}
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// parseLogfmtLine parses a line into a record.
+func parseLogfmtLine(line []byte) (map[string]string, error) {
+	record := make(map[string]string)
+	i := 0
+	for {
+		for i < len(line) && line[i] <= ' ' {
+			i++
+		}
+		if i >= len(line) {
+			return record, nil
+		}
+
+		start := i
+		for i < len(line) && line[i] > ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		if i == start {
+			return nil, errors.Newf("unexpected %q at column %d", line[i], i+1)
+		}
+		key := string(line[start:i])
+		if i >= len(line) || line[i] != '=' {
+			record[key] = "true"
+			continue
+		}
+
+		i++
+		if i < len(line) && line[i] == '"' {
+			start = i
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) {
+				return nil, errors.Newf("unterminated quoted value of key %q", key)
+			}
+			i++
+			value, err := strconv.Unquote(string(line[start:i]))
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid quoted value of key %q", key)
+			}
+			record[key] = value
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] > ' ' {
+			i++
+		}
+		record[key] = string(line[start:i])
+	}
+}
@@ -0,0 +1,88 @@
+package parser_test
+
+import (
+	"strings"
+
+	"github.com/tlipoca9/yevna/parser"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LogfmtParser", func() {
+	var got []map[string]string
+
+	BeforeEach(func() {
+		got = nil
+	})
+
+	When("input is empty", func() {
+		It("return empty object", func() {
+			err := parser.Logfmt().Unmarshal([]byte(""), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(BeEmpty())
+		})
+	})
+
+	When("input is slog text", func() {
+		It("return expected object", func() {
+			err := parser.Logfmt().Unmarshal([]byte(`
+time=2024-03-21T10:00:00.000Z level=INFO msg="hello world" dur=12ms
+time=2024-03-21T10:00:01.000Z level=ERROR msg="quote \" and\tescape" err="a=b c" debug
+
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]string{
+				{
+					"time":  "2024-03-21T10:00:00.000Z",
+					"level": "INFO",
+					"msg":   "hello world",
+					"dur":   "12ms",
+				},
+				{
+					"time":  "2024-03-21T10:00:01.000Z",
+					"level": "ERROR",
+					"msg":   "quote \" and\tescape",
+					"err":   "a=b c",
+					"debug": "true",
+				},
+			}))
+		})
+	})
+
+	When("value is empty", func() {
+		It("return expected object", func() {
+			err := parser.Logfmt().Unmarshal([]byte(`a= b="" c`), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]string{{"a": "", "b": "", "c": "true"}}))
+		})
+	})
+
+	When("quoted value is unterminated", func() {
+		It("return error", func() {
+			err := parser.Logfmt().Unmarshal([]byte(`msg="hello`), &got)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Context("Stream", func() {
+		It("decode record by record", func() {
+			type record struct {
+				Level string `logfmt:"level"`
+				Msg   string `logfmt:"msg"`
+			}
+			var records []record
+			r := strings.NewReader("level=info msg=a\nlevel=warn msg=\"b c\"")
+			err := parser.Logfmt().Stream(r, func(decode func(v any) error) error {
+				var r record
+				if err := decode(&r); err != nil {
+					return err
+				}
+				records = append(records, r)
+				return nil
+			})
+			Expect(err).To(BeNil())
+			Expect(records).To(Equal([]record{{"info", "a"}, {"warn", "b c"}}))
+		})
+	})
+})
@@ -1,11 +1,87 @@
+// Package parser provides the parsers and encoders of the formats supported by yevna.
+//
+// The parsers are immutable after construction: the With* methods return modified copies
+// and leave the receiver unchanged, so a parser can be shared across goroutines.
+// This is a breaking change, code which ignores the result of a With* method,
+// e.g. p.WithComma(';'), must use the returned parser instead.
 package parser
 
+import (
+	"io"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
 type Func func(b []byte, v any) error
 
 func (f Func) Unmarshal(b []byte, v any) error {
 	return f(b, v)
 }
 
+// Parser unmarshals bytes into v.
+// The parsers in this package are immutable after construction,
+// the With* methods return modified copies, so a parser can be shared across goroutines.
 type Parser interface {
 	Unmarshal([]byte, any) error
 }
+
+type EncoderFunc func(v any) ([]byte, error)
+
+func (f EncoderFunc) Marshal(v any) ([]byte, error) {
+	return f(v)
+}
+
+type Encoder interface {
+	Marshal(any) ([]byte, error)
+}
+
+// StreamParser is a Parser which parses the records one by one.
+type StreamParser interface {
+	Parser
+	// Stream reads the records from r one by one,
+	// and calls fn with a function which decodes the current record into v.
+	Stream(r io.Reader, fn func(decode func(v any) error) error) error
+}
+
+// with returns a modified copy of p, so that parsers are immutable after construction
+// and can be shared across goroutines.
+func with[T any](p *T, f func(c *T)) *T {
+	c := *p
+	f(&c)
+	return &c
+}
+
+// copyConfig returns a copy of conf, so that modifying conf after passing it has no effect.
+func copyConfig(conf *mapstructure.DecoderConfig) *mapstructure.DecoderConfig {
+	if conf == nil {
+		return nil
+	}
+	c := *conf
+	return &c
+}
+
+// newDecoder returns a decoder which decodes into result.
+// It never modifies conf, and uses tagName if conf doesn't specify one.
+func newDecoder(
+	conf *mapstructure.DecoderConfig,
+	tagName string,
+	result any,
+) (*mapstructure.Decoder, error) {
+	var c mapstructure.DecoderConfig
+	if conf != nil {
+		c = *conf
+	}
+	if c.TagName == "" {
+		c.TagName = tagName
+	}
+	c.Result = result
+	return mapstructure.NewDecoder(&c)
+}
+
+// tagName returns the tag name of conf, or def if conf doesn't specify one.
+func tagName(conf *mapstructure.DecoderConfig, def string) string {
+	if conf == nil || conf.TagName == "" {
+		return def
+	}
+	return conf.TagName
+}
@@ -0,0 +1,137 @@
+package parser_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tlipoca9/yevna/parser"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parser", func() {
+	It("does not modify the parser when configuring it", func() {
+		p := parser.CSV()
+		q := p.WithComma(';')
+
+		var got []map[string]string
+		Expect(p.Unmarshal([]byte("a,b\n1,2\n"), &got)).To(Succeed())
+		Expect(got).To(Equal([]map[string]string{{"a": "1", "b": "2"}}))
+
+		got = nil
+		Expect(q.Unmarshal([]byte("a;b\n1;2\n"), &got)).To(Succeed())
+		Expect(got).To(Equal([]map[string]string{{"a": "1", "b": "2"}}))
+	})
+
+	DescribeTable("leaves the receiver unchanged after With*",
+		func(newParser func() (parser.Parser, func()), input string) {
+			p, configure := newParser()
+			unmarshal := func() any {
+				var v any
+				Expect(p.Unmarshal([]byte(input), &v)).To(Succeed())
+				return v
+			}
+
+			before := unmarshal()
+			configure()
+			Expect(unmarshal()).To(Equal(before))
+		},
+		Entry("CSV", func() (parser.Parser, func()) {
+			p := parser.CSV()
+			return p, func() {
+				p.WithComma(';').WithHeaders("x", "y").WithDuplicateHeader(parser.DuplicateKeyError)
+			}
+		}, "a,a\n1,2\n"),
+		Entry("INI", func() (parser.Parser, func()) {
+			p := parser.INI()
+			return p, func() { p.WithDuplicateKey(parser.DuplicateKeyError) }
+		}, "a = 1\na = 2\n"),
+		Entry("PipeTable", func() (parser.Parser, func()) {
+			p := parser.PipeTable()
+			return p, func() {
+				p.WithCallback(func(k, v string) (string, string) { return strings.ToUpper(k), v })
+			}
+		}, "| a | b |\n|---|---|\n| 1 | 2 |\n"),
+		Entry("Table", func() (parser.Parser, func()) {
+			p := parser.Table()
+			return p, func() { p.WithNoHeader().WithColumns(parser.ColumnOffset("x", 0, 1)) }
+		}, "A  B\n1  2\n"),
+		Entry("XML", func() (parser.Parser, func()) {
+			p := parser.XML()
+			return p, func() { p.WithAttrPrefix("_").WithTextKey("text") }
+		}, `<a b="1">c</a>`),
+	)
+
+	It("reuses the parser with different headers", func() {
+		p := parser.CSV()
+
+		var first, second []map[string]string
+		Expect(p.Unmarshal([]byte("a,b\n1,2\n"), &first)).To(Succeed())
+		Expect(p.Unmarshal([]byte("c,d\n3,4\n"), &second)).To(Succeed())
+		Expect(first).To(Equal([]map[string]string{{"a": "1", "b": "2"}}))
+		Expect(second).To(Equal([]map[string]string{{"c": "3", "d": "4"}}))
+	})
+
+	DescribeTable(
+		"is safe for concurrent use",
+		func(
+			p parser.Parser,
+			format func(i int) string,
+			expected func(i int) any,
+			newResult func() any,
+		) {
+			var wg sync.WaitGroup
+			errs := make([]error, 50)
+			for i := range errs {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					v := newResult()
+					errs[i] = p.Unmarshal([]byte(format(i)), v)
+					Expect(v).To(Equal(expected(i)))
+				}()
+			}
+			wg.Wait()
+			for _, err := range errs {
+				Expect(err).To(BeNil())
+			}
+		},
+		Entry("CSV", parser.CSV(),
+			func(i int) string { return fmt.Sprintf("h%d,x\n%d,%d\n", i, i, i) },
+			func(i int) any {
+				k := fmt.Sprintf("h%d", i)
+				v := fmt.Sprint(i)
+				return &[]map[string]string{{k: v, "x": v}}
+			},
+			func() any { return &[]map[string]string{} },
+		),
+		Entry("Table", parser.Table(),
+			func(i int) string {
+				return fmt.Sprintf("KEY%02d  VALUE\nk%02d    %02d\n", i, i, i)
+			},
+			func(i int) any {
+				return &[]map[string]string{
+					{
+						fmt.Sprintf("KEY%02d", i): fmt.Sprintf("k%02d", i),
+						"VALUE":                   fmt.Sprintf("%02d", i),
+					},
+				}
+			},
+			func() any { return &[]map[string]string{} },
+		),
+		Entry("Sep", parser.Sep(),
+			func(i int) string { return strings.Repeat(fmt.Sprintf("%d ", i), i%5+1) },
+			func(i int) any {
+				ret := make([]string, 0, i%5+1)
+				for range i%5 + 1 {
+					ret = append(ret, fmt.Sprint(i))
+				}
+				return &ret
+			},
+			func() any { return &[]string{} },
+		),
+	)
+})
@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"unicode"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// PipeTableParser parses tables whose cells are separated by vertical bars, such as
+//   - Markdown tables with alignment rows, e.g. `| a | b |` and `|:--|--:|`.
+//   - ASCII grids printed by mysql, e.g. `+---+---+`.
+//   - psql output, e.g. ` a | b ` and `---+---`.
+//   - Unicode box-drawing tables, e.g. `│ a │ b │` and `├───┼───┤`.
+//
+// Border and separator lines are skipped, the first remaining line is the header,
+// and each other line is a record decoded as map[string]any.
+// Lines without vertical bars after the header, e.g. the `(2 rows)` footer of psql, are skipped.
+type PipeTableParser struct {
+	conf *mapstructure.DecoderConfig
+	cb   func(k, v string) (string, string)
+}
+
+func (p *PipeTableParser) WithDecoderConfig(conf *mapstructure.DecoderConfig) *PipeTableParser {
+	return with(p, func(c *PipeTableParser) { c.conf = copyConfig(conf) })
+}
+
+func (p *PipeTableParser) WithCallback(f func(k, v string) (string, string)) *PipeTableParser {
+	return with(p, func(c *PipeTableParser) { c.cb = f })
+}
+
+// resolved returns a copy of p with the defaults filled in.
+func (p *PipeTableParser) resolved() *PipeTableParser {
+	c := *p
+	if c.cb == nil {
+		c.cb = func(k, v string) (string, string) {
+			return strings.TrimSpace(k), strings.TrimSpace(v)
+		}
+	}
+	return &c
+}
+
+// PipeTable returns a new PipeTableParser
+func PipeTable() *PipeTableParser {
+	return &PipeTableParser{}
+}
+
+func (p *PipeTableParser) Unmarshal(b []byte, v any) error {
+	p = p.resolved()
+
+	dec, err := newDecoder(p.conf, "json", v)
+	if err != nil {
+		return errors.Wrap(err, "failed to create decoder")
+	}
+
+	var (
+		header            []string
+		leading, trailing bool
+		ret               = make([]map[string]any, 0)
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || isBorderLine(trimmed) {
+			continue
+		}
+		if header == nil {
+			// whether the table has outer bars is decided by the header,
+			// so that an empty first or last cell of psql output isn't taken as a border
+			rs := []rune(trimmed)
+			leading = isBar(rs[0])
+			trailing = len(rs) > 1 && isBar(rs[len(rs)-1]) && rs[len(rs)-2] != '\\'
+			header = splitPipeRow(trimmed, leading, trailing)
+			continue
+		}
+		if len(header) > 1 && !strings.ContainsFunc(line, isBar) {
+			continue
+		}
+
+		cells := splitPipeRow(line, leading, trailing)
+		if len(cells) > len(header) {
+			return errors.Newf(
+				"line %d: expected at most %d cells, got %d",
+				lineno,
+				len(header),
+				len(cells),
+			)
+		}
+		item := make(map[string]any, len(header))
+		for i, h := range header {
+			var cell string
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			k, v := p.cb(h, cell)
+			item[k] = v
+		}
+		ret = append(ret, item)
+	}
+	if scanner.Err() != nil {
+		return errors.Wrap(scanner.Err(), "failed to scan")
+	}
+
+	err = dec.Decode(ret)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode")
+	}
+	return nil
+}
+
+// isBar reports whether r separates the cells.
+func isBar(r rune) bool {
+	switch r {
+	case '|', '│', '┃', '║', '┆', '┇', '┊', '┋':
+		return true
+	}
+	return false
+}
+
+// isBoxDrawing reports whether r is a box-drawing character.
+func isBoxDrawing(r rune) bool {
+	return r >= 0x2500 && r <= 0x257F
+}
+
+// isBorderLine reports whether the line is a border or separator line,
+// which only consists of rules, junctions and vertical bars.
+func isBorderLine(line string) bool {
+	for _, r := range line {
+		if !isBoxDrawing(r) && !isBar(r) && !unicode.IsSpace(r) &&
+			!strings.ContainsRune("-=+:", r) {
+			return false
+		}
+	}
+	return strings.ContainsFunc(line, func(r rune) bool { return isBoxDrawing(r) && !isBar(r) }) ||
+		strings.Contains(line, "--") || strings.Contains(line, "==") ||
+		strings.Contains(line, ":-") || strings.Contains(line, "-:")
+}
+
+// splitPipeRow splits the row into cells, `\|` is an escaped vertical bar.
+// If the table has the leading or trailing bar, the blank cell before or after it is dropped.
+func splitPipeRow(line string, leading, trailing bool) []string {
+	var (
+		cells []string
+		cell  strings.Builder
+	)
+	rs := []rune(line)
+	for i := 0; i < len(rs); i++ {
+		switch {
+		case rs[i] == '\\' && i+1 < len(rs) && rs[i+1] == '|':
+			cell.WriteRune('|')
+			i++
+		case isBar(rs[i]):
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteRune(rs[i])
+		}
+	}
+	cells = append(cells, cell.String())
+
+	if leading && len(cells) > 1 && strings.TrimSpace(cells[0]) == "" {
+		cells = cells[1:]
+	}
+	if trailing && len(cells) > 1 && strings.TrimSpace(cells[len(cells)-1]) == "" {
+		cells = cells[:len(cells)-1]
+	}
+	return cells
+}
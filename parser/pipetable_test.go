@@ -0,0 +1,135 @@
+package parser_test
+
+import (
+	"github.com/tlipoca9/yevna/parser"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PipeTableParser", func() {
+	p := parser.PipeTable()
+	var got []map[string]any
+
+	BeforeEach(func() {
+		got = nil
+	})
+
+	expected := []map[string]any{
+		{"id": "1", "name": "Alice", "email": "alice@example.com"},
+		{"id": "2", "name": "Bob", "email": ""},
+	}
+
+	When("input is empty", func() {
+		It("return empty object", func() {
+			err := p.Unmarshal([]byte(""), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(BeEmpty())
+		})
+	})
+
+	When("input is markdown", func() {
+		It("return expected object", func() {
+			err := p.Unmarshal([]byte(`
+| id | name  | email             |
+|---:|:------|:-----------------:|
+| 1  | Alice | alice@example.com |
+| 2  | Bob   |                   |
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(expected))
+		})
+	})
+
+	When("input is markdown with escaped bars", func() {
+		It("return expected object", func() {
+			err := p.Unmarshal([]byte(`
+| expr | result |
+| ---- | ------ |
+| a \| b | - |
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{{"expr": "a | b", "result": "-"}}))
+		})
+	})
+
+	When("input is mysql", func() {
+		It("return expected object", func() {
+			err := p.Unmarshal([]byte(`
++----+-------+-------------------+
+| id | name  | email             |
++----+-------+-------------------+
+|  1 | Alice | alice@example.com |
+|  2 | Bob   |                   |
++----+-------+-------------------+
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(expected))
+		})
+	})
+
+	When("input is psql", func() {
+		It("return expected object", func() {
+			err := p.Unmarshal([]byte(`
+ id | name  |       email
+----+-------+-------------------
+  1 | Alice | alice@example.com
+  2 | Bob   |
+(2 rows)
+
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(expected))
+		})
+	})
+
+	When("input is psql with an empty first cell", func() {
+		It("keeps the cell", func() {
+			err := p.Unmarshal([]byte(`
+ id | name
+----+------
+    | Bob
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{{"id": "", "name": "Bob"}}))
+		})
+	})
+
+	When("input is psql with an empty last cell", func() {
+		It("keeps the cell", func() {
+			err := p.Unmarshal([]byte(`
+ id | name
+----+------
+  1 |
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{{"id": "1", "name": ""}}))
+		})
+	})
+
+	When("input is unicode box-drawing", func() {
+		It("return expected object", func() {
+			err := p.Unmarshal([]byte(`
+┌────┬───────┬───────────────────┐
+│ id │ name  │ email             │
+├────┼───────┼───────────────────┤
+│ 1  │ Alice │ alice@example.com │
+│ 2  │ Bob   │                   │
+└────┴───────┴───────────────────┘
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(expected))
+		})
+	})
+
+	When("a row has too many cells", func() {
+		It("return error", func() {
+			err := p.Unmarshal([]byte(`
+| a | b |
+|---|---|
+| 1 | 2 | 3 |
+`[1:]), &got)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
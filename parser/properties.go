@@ -0,0 +1,278 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// PropertiesFile is the document of a Java properties file.
+// It keeps the order of entries and the comments,
+// so that decoding into PropertiesFile and encoding it back preserves them.
+type PropertiesFile struct {
+	Entries []*PropertiesEntry
+	// Comments after the last entry.
+	Comments []string
+}
+
+// Get returns the value of the last entry with the key.
+func (f *PropertiesFile) Get(key string) (string, bool) {
+	for i := len(f.Entries) - 1; i >= 0; i-- {
+		if f.Entries[i].Key == key {
+			return f.Entries[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// PropertiesEntry is an entry of PropertiesFile.
+type PropertiesEntry struct {
+	Key   string
+	Value string
+	// Comments and blank lines before the entry, including the comment markers.
+	Comments []string
+}
+
+// PropertiesParser parses and encodes Java properties files,
+// following the format of java.util.Properties.load:
+//   - lines starting with '#' or '!' are comments.
+//   - keys and values are separated by '=', ':' or whitespaces.
+//   - a line ending with an odd number of '\' is continued on the next line.
+//   - escapes such as '\t', '\n' and '\uXXXX' are decoded.
+//
+// The properties are decoded as a flat map, the last value of duplicate keys wins.
+// Decoding into *PropertiesFile keeps the comments.
+type PropertiesParser struct {
+	conf *mapstructure.DecoderConfig
+}
+
+func (p *PropertiesParser) WithDecoderConfig(conf *mapstructure.DecoderConfig) *PropertiesParser {
+	return with(p, func(c *PropertiesParser) { c.conf = copyConfig(conf) })
+}
+
+// Properties returns a new PropertiesParser
+func Properties() *PropertiesParser {
+	return &PropertiesParser{}
+}
+
+func (p *PropertiesParser) Unmarshal(b []byte, v any) error {
+	f, err := parseProperties(b)
+	if err != nil {
+		return errors.Wrapf(err, "unmarshal failed")
+	}
+	if ff, ok := v.(*PropertiesFile); ok {
+		*ff = *f
+		return nil
+	}
+
+	raw := make(map[string]string, len(f.Entries))
+	for _, e := range f.Entries {
+		raw[e.Key] = e.Value
+	}
+
+	dec, err := newDecoder(p.conf, "properties", v)
+	if err != nil {
+		return errors.Wrapf(err, "create decoder failed")
+	}
+	err = dec.Decode(raw)
+	if err != nil {
+		return errors.Wrapf(err, "decode failed")
+	}
+	return nil
+}
+
+// parseProperties parses b into PropertiesFile.
+func parseProperties(b []byte) (*PropertiesFile, error) {
+	f := &PropertiesFile{}
+
+	var comments []string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			comments = append(comments, line)
+			continue
+		}
+		for continued(line) && scanner.Scan() {
+			lineno++
+			line = line[:len(line)-1] + strings.TrimLeft(scanner.Text(), " \t\f")
+		}
+
+		key, value := splitProperty(line)
+		k, err := unescapeProperty(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineno)
+		}
+		v, err := unescapeProperty(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineno)
+		}
+		f.Entries = append(f.Entries, &PropertiesEntry{Key: k, Value: v, Comments: comments})
+		comments = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	f.Comments = comments
+	return f, nil
+}
+
+// continued reports whether the line ends with an odd number of '\'.
+func continued(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+// splitProperty splits the line at the first unescaped separator.
+func splitProperty(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return line[:i], strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			value := strings.TrimLeft(line[i:], " \t\f")
+			if value != "" && (value[0] == '=' || value[0] == ':') {
+				value = strings.TrimLeft(value[1:], " \t\f")
+			}
+			return line[:i], value
+		}
+	}
+	return line, ""
+}
+
+// unescapeProperty decodes the escapes in s.
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(s) {
+				return "", errors.Newf("malformed \\uxxxx escape in %q", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", errors.Newf("malformed \\uxxxx escape in %q", s)
+			}
+			i += 4
+			// combine surrogate pairs
+			if utf16.IsSurrogate(rune(r)) && i+6 < len(s) && s[i+1] == '\\' && s[i+2] == 'u' {
+				r2, err := strconv.ParseUint(s[i+3:i+7], 16, 16)
+				if err == nil {
+					sb.WriteRune(utf16.DecodeRune(rune(r), rune(r2)))
+					i += 6
+					continue
+				}
+			}
+			sb.WriteRune(rune(r))
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String(), nil
+}
+
+// Marshal encodes v as properties.
+// If v is PropertiesFile or *PropertiesFile, the order and comments are preserved.
+// Otherwise, v is decoded into map[string]any using mapstructure,
+// and the keys are written in lexical order.
+// Characters outside of printable ASCII are written as '\uXXXX' escapes.
+func (p *PropertiesParser) Marshal(v any) ([]byte, error) {
+	switch f := v.(type) {
+	case *PropertiesFile:
+		return f.bytes(), nil
+	case PropertiesFile:
+		return f.bytes(), nil
+	}
+
+	m, err := toMap(v, tagName(p.conf, "properties"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "marshal failed")
+	}
+	f := &PropertiesFile{}
+	for _, k := range sortedKeys(m) {
+		f.Entries = append(f.Entries, &PropertiesEntry{Key: k, Value: toStr(m[k])})
+	}
+	return f.bytes(), nil
+}
+
+func (f *PropertiesFile) bytes() []byte {
+	var buf bytes.Buffer
+	for _, e := range f.Entries {
+		for _, c := range e.Comments {
+			buf.WriteString(c)
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(escapeProperty(e.Key, true))
+		buf.WriteByte('=')
+		buf.WriteString(escapeProperty(e.Value, false))
+		buf.WriteByte('\n')
+	}
+	for _, c := range f.Comments {
+		buf.WriteString(c)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// escapeProperty escapes s as a key or a value.
+func escapeProperty(s string, isKey bool) string {
+	var sb strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			if isKey || i == 0 {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(r)
+		case ' ':
+			if isKey || i == 0 {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(r)
+		default:
+			if r < 0x20 || r > 0x7e {
+				for _, c := range utf16.Encode([]rune{r}) {
+					sb.WriteString(`\u`)
+					sb.WriteString(strings.ToUpper(strconv.FormatUint(uint64(c)|0x10000, 16)[1:]))
+				}
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
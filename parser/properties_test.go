@@ -0,0 +1,95 @@
+package parser_test
+
+import (
+	"github.com/tlipoca9/yevna/parser"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PropertiesParser", func() {
+	input := []byte(`
+# application settings
+! another comment
+app.name = demo
+app.greeting:Hello\tWorld
+app.description   A long \
+                  description
+key\ with\ spaces = value
+unicode = café 😀
+empty
+
+app.name = overridden
+`[1:])
+
+	When("input is empty", func() {
+		It("return empty object", func() {
+			var got map[string]string
+			err := parser.Properties().Unmarshal([]byte(""), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(BeEmpty())
+		})
+	})
+
+	When("input is complex", func() {
+		It("return expected object", func() {
+			var got map[string]string
+			err := parser.Properties().Unmarshal(input, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(map[string]string{
+				"app.name":        "overridden",
+				"app.greeting":    "Hello\tWorld",
+				"app.description": "A long description",
+				"key with spaces": "value",
+				"unicode":         "café 😀",
+				"empty":           "",
+			}))
+		})
+	})
+
+	When("input has malformed unicode escape", func() {
+		It("return error", func() {
+			var got map[string]string
+			err := parser.Properties().Unmarshal([]byte(`key = \u12`), &got)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Context("Marshal", func() {
+		It("preserve comments on round trip", func() {
+			p := parser.Properties()
+			var f parser.PropertiesFile
+			err := p.Unmarshal(input, &f)
+			Expect(err).To(BeNil())
+			v, ok := f.Get("app.name")
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal("overridden"))
+
+			b, err := p.Marshal(&f)
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal(`
+# application settings
+! another comment
+app.name=demo
+app.greeting=Hello\tWorld
+app.description=A long description
+key\ with\ spaces=value
+unicode=caf\u00E9 \uD83D\uDE00
+empty=
+
+app.name=overridden
+`[1:]))
+
+			var got parser.PropertiesFile
+			err = p.Unmarshal(b, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(f))
+		})
+
+		It("encode map", func() {
+			b, err := parser.Properties().Marshal(map[string]any{"b": 1, "a": " x=y"})
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal("a=\\ x=y\nb=1\n"))
+		})
+	})
+})
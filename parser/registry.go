@@ -0,0 +1,339 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"mime"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/goccy/go-json"
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Format describes a data format known by a Registry.
+type Format struct {
+	// Name is the unique name of the format, e.g. "yaml".
+	Name string
+	// Extensions are the file extensions of the format, e.g. ".yaml" and ".yml".
+	Extensions []string
+	// MIMETypes are the media types of the format, e.g. "application/yaml".
+	MIMETypes []string
+	// Parser unmarshals the format.
+	Parser Parser
+	// Encoder marshals the format, it is nil if the format can't be encoded.
+	Encoder Encoder
+	// Detect reports whether b looks like the format, it is nil if the format can't be sniffed.
+	Detect func(b []byte) bool
+}
+
+// Registry maps names, file extensions and MIME types to formats.
+// It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	formats []Format
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// DefaultRegistry is the Registry with the formats of this package,
+// which is used by Lookup, ByExtension, ByMIME and Detect.
+var DefaultRegistry = NewRegistry()
+
+func init() {
+	for _, f := range builtinFormats() {
+		if err := DefaultRegistry.Register(f); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register adds f to the registry.
+// Formats registered later take precedence when the extensions, MIME types or detections overlap.
+func (r *Registry) Register(f Format) error {
+	if f.Name == "" {
+		return errors.New("format name is empty")
+	}
+	if f.Parser == nil {
+		return errors.Newf("format %q has no parser", f.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if slices.ContainsFunc(r.formats, func(e Format) bool { return e.Name == f.Name }) {
+		return errors.Newf("format %q is already registered", f.Name)
+	}
+	f.Extensions = slices.Clone(f.Extensions)
+	for i, ext := range f.Extensions {
+		f.Extensions[i] = strings.ToLower(ext)
+	}
+	f.MIMETypes = slices.Clone(f.MIMETypes)
+	r.formats = append(r.formats, f)
+	return nil
+}
+
+// find returns the last registered format matched by fn.
+func (r *Registry) find(fn func(f Format) bool) (Format, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.formats) - 1; i >= 0; i-- {
+		if fn(r.formats[i]) {
+			return r.formats[i], true
+		}
+	}
+	return Format{}, false
+}
+
+// Lookup returns the format with the name.
+func (r *Registry) Lookup(name string) (Format, bool) {
+	return r.find(func(f Format) bool { return f.Name == name })
+}
+
+// ByExtension returns the format of the file path by its extension, e.g. "config.yaml".
+func (r *Registry) ByExtension(path string) (Format, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return Format{}, false
+	}
+	return r.find(func(f Format) bool { return slices.Contains(f.Extensions, ext) })
+}
+
+// ByMIME returns the format of the content type, e.g. "application/json; charset=utf-8".
+// Structured syntax suffixes such as "application/problem+json" fall back to the suffix.
+func (r *Registry) ByMIME(contentType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return Format{}, false
+	}
+	if f, ok := r.find(func(f Format) bool { return slices.Contains(f.MIMETypes, mediaType) }); ok {
+		return f, true
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		return r.Lookup(mediaType[i+1:])
+	}
+	return Format{}, false
+}
+
+// Detect sniffs the format of b.
+func (r *Registry) Detect(b []byte) (Format, bool) {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return Format{}, false
+	}
+	return r.find(func(f Format) bool { return f.Detect != nil && f.Detect(b) })
+}
+
+// Lookup is the shortcut for DefaultRegistry.Lookup
+func Lookup(name string) (Format, bool) {
+	return DefaultRegistry.Lookup(name)
+}
+
+// ByExtension is the shortcut for DefaultRegistry.ByExtension
+func ByExtension(path string) (Format, bool) {
+	return DefaultRegistry.ByExtension(path)
+}
+
+// ByMIME is the shortcut for DefaultRegistry.ByMIME
+func ByMIME(contentType string) (Format, bool) {
+	return DefaultRegistry.ByMIME(contentType)
+}
+
+// Detect is the shortcut for DefaultRegistry.Detect
+func Detect(b []byte) (Format, bool) {
+	return DefaultRegistry.Detect(b)
+}
+
+// Register is the shortcut for DefaultRegistry.Register
+func Register(f Format) error {
+	return DefaultRegistry.Register(f)
+}
+
+// builtinFormats returns the formats of this package.
+// They are ordered from the least to the most specific detection,
+// since the formats registered later are detected first.
+func builtinFormats() []Format {
+	return []Format{
+		{
+			Name:       "properties",
+			Extensions: []string{".properties"},
+			MIMETypes:  []string{"text/x-java-properties"},
+			Parser:     Properties(),
+			Encoder:    Properties(),
+		},
+		{
+			Name:       "dotenv",
+			Extensions: []string{".env"},
+			Parser:     Dotenv(),
+			Detect:     detectDotenv,
+		},
+		{
+			Name:       "logfmt",
+			Extensions: []string{".logfmt"},
+			MIMETypes:  []string{"application/logfmt"},
+			Parser:     Logfmt(),
+			Detect:     detectLogfmt,
+		},
+		{
+			Name:       "csv",
+			Extensions: []string{".csv"},
+			MIMETypes:  []string{"text/csv"},
+			Parser:     CSV(),
+			Detect:     func(b []byte) bool { return detectCSV(b, ',') },
+		},
+		{
+			Name:       "tsv",
+			Extensions: []string{".tsv"},
+			MIMETypes:  []string{"text/tab-separated-values"},
+			Parser:     TSV(),
+			Detect:     func(b []byte) bool { return detectCSV(b, '\t') },
+		},
+		{
+			Name:       "yaml",
+			Extensions: []string{".yaml", ".yml"},
+			MIMETypes: []string{
+				"application/yaml",
+				"application/x-yaml",
+				"text/yaml",
+				"text/x-yaml",
+			},
+			Parser:  YAML(),
+			Encoder: EncoderFunc(yaml.Marshal),
+			Detect:  detectYAML,
+		},
+		{
+			Name:       "ini",
+			Extensions: []string{".ini", ".cfg"},
+			Parser:     INI(),
+			Encoder:    INI(),
+			Detect:     detectINI,
+		},
+		{
+			Name:       "toml",
+			Extensions: []string{".toml"},
+			MIMETypes:  []string{"application/toml"},
+			Parser:     TOML(),
+			Encoder:    EncoderFunc(toml.Marshal),
+			Detect:     detectTOML,
+		},
+		{
+			Name:       "xml",
+			Extensions: []string{".xml"},
+			MIMETypes:  []string{"application/xml", "text/xml"},
+			Parser:     XML(),
+			Encoder:    XML(),
+			Detect:     detectXML,
+		},
+		{
+			Name:       "json",
+			Extensions: []string{".json"},
+			MIMETypes:  []string{"application/json", "text/json"},
+			Parser:     JSON(),
+			Encoder:    EncoderFunc(json.Marshal),
+			Detect:     detectJSON,
+		},
+	}
+}
+
+// contentLines returns the non-empty lines of b without comments starting with one of the prefixes.
+func contentLines(b []byte, commentPrefixes ...string) []string {
+	var ret []string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" ||
+			slices.ContainsFunc(
+				commentPrefixes,
+				func(p string) bool { return strings.HasPrefix(line, p) },
+			) {
+			continue
+		}
+		ret = append(ret, line)
+	}
+	return ret
+}
+
+func detectJSON(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	return (b[0] == '{' || b[0] == '[') && json.Valid(b)
+}
+
+func detectXML(b []byte) bool {
+	return bytes.TrimSpace(b)[0] == '<'
+}
+
+func detectTOML(b []byte) bool {
+	var v map[string]any
+	return toml.Unmarshal(b, &v) == nil && len(v) != 0
+}
+
+func detectYAML(b []byte) bool {
+	var v any
+	if yaml.Unmarshal(b, &v) != nil {
+		return false
+	}
+	switch v.(type) {
+	case map[string]any, []any:
+		return true
+	}
+	return false
+}
+
+var (
+	iniSectionRe = regexp.MustCompile(`^\[[^\[\]]+\]$`)
+	iniKeyRe     = regexp.MustCompile(`^[^=:\s][^=:]*[=:]`)
+	dotenvRe     = regexp.MustCompile(`^(export\s+)?[A-Za-z_][A-Za-z0-9_.]*\s*=`)
+)
+
+func detectINI(b []byte) bool {
+	ls := contentLines(b, "#", ";")
+	if len(ls) == 0 || !iniSectionRe.MatchString(ls[0]) {
+		return false
+	}
+	for _, l := range ls {
+		if !iniSectionRe.MatchString(l) && !iniKeyRe.MatchString(l) {
+			return false
+		}
+	}
+	return true
+}
+
+func detectDotenv(b []byte) bool {
+	ls := contentLines(b, "#")
+	for _, l := range ls {
+		if !dotenvRe.MatchString(l) {
+			return false
+		}
+	}
+	return len(ls) != 0
+}
+
+// detectLogfmt reports whether each line is logfmt and there are lines with multiple pairs,
+// so that it doesn't take dotenv as logfmt.
+func detectLogfmt(b []byte) bool {
+	multiple := false
+	for _, l := range contentLines(b) {
+		record, err := parseLogfmtLine([]byte(l))
+		if err != nil || !strings.Contains(l, "=") || strings.HasPrefix(l, "export ") {
+			return false
+		}
+		multiple = multiple || len(record) > 1
+	}
+	return multiple
+}
+
+// detectCSV reports whether b has at least two lines,
+// and the same number of fields (at least two) in each line.
+func detectCSV(b []byte, comma rune) bool {
+	cr := csv.NewReader(bytes.NewReader(b))
+	cr.Comma = comma
+	records, err := cr.ReadAll()
+	return err == nil && len(records) > 1 && len(records[0]) > 1
+}
@@ -0,0 +1,97 @@
+package parser_test
+
+import (
+	"strings"
+
+	"github.com/tlipoca9/yevna/parser"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	DescribeTable("ByExtension",
+		func(path, expected string) {
+			f, ok := parser.ByExtension(path)
+			Expect(ok).To(BeTrue())
+			Expect(f.Name).To(Equal(expected))
+		},
+		Entry("yaml", "config.yaml", "yaml"),
+		Entry("yml in upper case", "/etc/app/CONFIG.YML", "yaml"),
+		Entry("json", "a/b.json", "json"),
+		Entry("toml", "Cargo.toml", "toml"),
+		Entry("dotenv", ".env", "dotenv"),
+		Entry("properties", "app.properties", "properties"),
+	)
+
+	DescribeTable("ByMIME",
+		func(contentType, expected string) {
+			f, ok := parser.ByMIME(contentType)
+			Expect(ok).To(BeTrue())
+			Expect(f.Name).To(Equal(expected))
+		},
+		Entry("json with charset", "application/json; charset=utf-8", "json"),
+		Entry("structured syntax suffix", "application/problem+json", "json"),
+		Entry("yaml", "application/x-yaml", "yaml"),
+		Entry("xml", "text/xml", "xml"),
+		Entry("csv", "text/csv", "csv"),
+	)
+
+	DescribeTable("Detect",
+		func(content, expected string) {
+			f, ok := parser.Detect([]byte(content))
+			Expect(ok).To(BeTrue())
+			Expect(f.Name).To(Equal(expected))
+		},
+		Entry("json object", `{"a": 1}`, "json"),
+		Entry("json array", "\n[1, 2]\n", "json"),
+		Entry("xml", `<?xml version="1.0"?><a/>`, "xml"),
+		Entry("toml", "title = \"x\"\n[owner]\nname = \"y\"\n", "toml"),
+		Entry("yaml", "a: 1\nb:\n  - c\n", "yaml"),
+		Entry("ini", "[server]\nhost = localhost\n", "ini"),
+		Entry("csv", "a,b\n1,2\n", "csv"),
+		Entry("tsv", "a\tb\n1\t2\n", "tsv"),
+		Entry("dotenv", "# comment\nexport FOO=bar\nBAZ=qux\n", "dotenv"),
+		Entry("logfmt", "level=info msg=\"hello world\"\nlevel=warn msg=bye\n", "logfmt"),
+	)
+
+	It("doesn't detect unknown content", func() {
+		_, ok := parser.Detect([]byte("hello world"))
+		Expect(ok).To(BeFalse())
+		_, ok = parser.Detect([]byte("  \n"))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("registers third-party formats", func() {
+		r := parser.NewRegistry()
+		upper := parser.Func(func(b []byte, v any) error {
+			*v.(*string) = strings.ToUpper(string(b))
+			return nil
+		})
+		Expect(r.Register(parser.Format{
+			Name:       "upper",
+			Extensions: []string{".UP"},
+			MIMETypes:  []string{"text/x-upper"},
+			Parser:     upper,
+			Detect:     func(b []byte) bool { return strings.HasPrefix(string(b), "UP:") },
+		})).To(Succeed())
+		Expect(r.Register(parser.Format{Name: "upper", Parser: upper})).NotTo(Succeed())
+		Expect(r.Register(parser.Format{Name: "nothing"})).NotTo(Succeed())
+
+		for _, lookup := range []func() (parser.Format, bool){
+			func() (parser.Format, bool) { return r.Lookup("upper") },
+			func() (parser.Format, bool) { return r.ByExtension("a.up") },
+			func() (parser.Format, bool) { return r.ByMIME("text/x-upper") },
+			func() (parser.Format, bool) { return r.Detect([]byte("UP:x")) },
+		} {
+			f, ok := lookup()
+			Expect(ok).To(BeTrue())
+			var got string
+			Expect(f.Parser.Unmarshal([]byte("up:x"), &got)).To(Succeed())
+			Expect(got).To(Equal("UP:X"))
+		}
+
+		_, ok := parser.Lookup("upper")
+		Expect(ok).To(BeFalse())
+	})
+})
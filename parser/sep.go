@@ -15,33 +15,27 @@ type SepParser struct {
 }
 
 func (p *SepParser) SplitFunc(f bufio.SplitFunc) *SepParser {
-	p.splitFunc = f
-	return p
+	return with(p, func(c *SepParser) { c.splitFunc = f })
 }
 
 func (p *SepParser) Filter(f func(token string) bool) *SepParser {
-	p.filter = f
-	return p
+	return with(p, func(c *SepParser) { c.filter = f })
 }
 
 func (p *SepParser) WithDecoderConfig(conf *mapstructure.DecoderConfig) *SepParser {
-	p.conf = conf
-	return p
+	return with(p, func(c *SepParser) { c.conf = copyConfig(conf) })
 }
 
-func (p *SepParser) lazyInit() {
-	if p.splitFunc == nil {
-		p.splitFunc = bufio.ScanWords
+// resolved returns a copy of p with the defaults filled in.
+func (p *SepParser) resolved() *SepParser {
+	c := *p
+	if c.splitFunc == nil {
+		c.splitFunc = bufio.ScanWords
 	}
-	if p.filter == nil {
-		p.filter = func(_ string) bool { return true }
-	}
-	if p.conf == nil {
-		p.conf = &mapstructure.DecoderConfig{TagName: "json"}
-	}
-	if p.conf.TagName == "" {
-		p.conf.TagName = "json"
+	if c.filter == nil {
+		c.filter = func(_ string) bool { return true }
 	}
+	return &c
 }
 
 // Sep returns a new SepParser
@@ -55,10 +49,9 @@ func Line() *SepParser {
 }
 
 func (p *SepParser) Unmarshal(b []byte, v any) error {
-	p.lazyInit()
+	p = p.resolved()
 
-	p.conf.Result = v
-	dec, err := mapstructure.NewDecoder(p.conf)
+	dec, err := newDecoder(p.conf, "json", v)
 	if err != nil {
 		return errors.Wrapf(err, "create decoder failed")
 	}
@@ -3,8 +3,10 @@ package parser
 import (
 	"bufio"
 	"bytes"
+	"slices"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/cockroachdb/errors"
 	"github.com/go-viper/mapstructure/v2"
@@ -12,26 +14,44 @@ import (
 
 type TableHeader string
 
+// Index returns the boundaries [start, end) of the columns in display columns,
+// which are found by the columns consisting of separators in the header and all lines.
+// East-Asian wide characters occupy two columns, and tabs are expanded to the next tab stop.
 func (h TableHeader) Index(isSep func(rune) bool, lines []string) ([][2]int, error) {
-	ret := make([][2]int, 0)
-	sepMap := make([]bool, len(h)+1)
-	for i, c := range h {
-		if !isSep(c) {
-			sepMap[i] = true
-		}
+	dl := make([]displayLine, 0, len(lines))
+	for _, line := range lines {
+		dl = append(dl, newDisplayLine(line))
 	}
+	return tableIndex(isSep, newDisplayLine(string(h)), dl)
+}
 
-	for _, line := range lines {
-		if len(sepMap) < len(line)+1 {
-			sepMap = append(sepMap, make([]bool, len(line)+1-len(sepMap))...)
+func tableIndex(isSep func(rune) bool, header displayLine, lines []displayLine) ([][2]int, error) {
+	ret := make([][2]int, 0)
+	sepMap := make([]bool, header.width+1)
+	mark := func(l displayLine) {
+		if len(sepMap) < l.width+1 {
+			sepMap = append(sepMap, make([]bool, l.width+1-len(sepMap))...)
 		}
-		for i, c := range line {
-			if !sepMap[i] && !isSep(c) {
-				sepMap[i] = true
+		for i, c := range l.runes {
+			if isSep(c) {
+				continue
+			}
+			// a wide character marks all the columns it occupies
+			end := l.width
+			if i+1 < len(l.cols) {
+				end = l.cols[i+1]
+			}
+			for col := l.cols[i]; col < max(end, l.cols[i]+1); col++ {
+				sepMap[col] = true
 			}
 		}
 	}
 
+	mark(header)
+	for _, line := range lines {
+		mark(line)
+	}
+
 	i := 0
 	for idx, v := range sepMap {
 		if i != -1 && !v {
@@ -48,6 +68,29 @@ func (h TableHeader) Index(isSep func(rune) bool, lines []string) ([][2]int, err
 	return ret, nil
 }
 
+// TableColumn declares a column of the table explicitly.
+// Columns are measured in display columns,
+// so East-Asian wide characters occupy two columns.
+type TableColumn struct {
+	name   string
+	anchor bool
+	start  int
+	end    int
+}
+
+// ColumnAnchor declares a column by its header text.
+// The column starts where the text appears in the header line,
+// and ends where the next column starts. The last column takes the rest of the line.
+func ColumnAnchor(header string) TableColumn {
+	return TableColumn{name: header, anchor: true}
+}
+
+// ColumnOffset declares a column by display column offsets [start, end).
+// If end is negative, the column takes the rest of the line.
+func ColumnOffset(name string, start, end int) TableColumn {
+	return TableColumn{name: name, start: start, end: end}
+}
+
 // TableParser is a builder for the TableParser
 type TableParser struct {
 	conf      *mapstructure.DecoderConfig
@@ -55,51 +98,60 @@ type TableParser struct {
 	filter    func(i int, line string) bool
 	cb        func(k, v string) (string, string)
 	headerTxt string
+	columns   []TableColumn
+	noHeader  bool
 }
 
 func (p *TableParser) WithDecoderConfig(conf *mapstructure.DecoderConfig) *TableParser {
-	p.conf = conf
-	return p
+	return with(p, func(c *TableParser) { c.conf = copyConfig(conf) })
 }
 
 func (p *TableParser) WithSepFunc(f func(rune) bool) *TableParser {
-	p.sepFunc = f
-	return p
+	return with(p, func(c *TableParser) { c.sepFunc = f })
 }
 
 func (p *TableParser) WithFilter(f func(i int, line string) bool) *TableParser {
-	p.filter = f
-	return p
+	return with(p, func(c *TableParser) { c.filter = f })
 }
 
 func (p *TableParser) WithCallback(f func(k, v string) (string, string)) *TableParser {
-	p.cb = f
-	return p
+	return with(p, func(c *TableParser) { c.cb = f })
 }
 
 func (p *TableParser) WithHeader(header string) *TableParser {
-	p.headerTxt = header
-	return p
+	return with(p, func(c *TableParser) { c.headerTxt = header })
+}
+
+// WithColumns declares the columns explicitly instead of inferring them from separator columns.
+// If a value overflows the end of a ColumnAnchor column in a line,
+// the column is extended to the end of the value;
+// ColumnOffset columns are sliced exactly at their offsets.
+func (p *TableParser) WithColumns(columns ...TableColumn) *TableParser {
+	return with(p, func(c *TableParser) { c.columns = slices.Clone(columns) })
 }
 
-func (p *TableParser) lazyInit() {
-	if p.sepFunc == nil {
-		p.sepFunc = unicode.IsSpace
+// WithNoHeader indicates that the input has no header line.
+// It is only valid with columns declared by ColumnOffset.
+func (p *TableParser) WithNoHeader() *TableParser {
+	return with(p, func(c *TableParser) { c.noHeader = true })
+}
+
+// resolved returns a copy of p with the defaults filled in.
+func (p *TableParser) resolved() *TableParser {
+	c := *p
+	if c.sepFunc == nil {
+		c.sepFunc = unicode.IsSpace
 	}
-	if p.filter == nil {
-		p.filter = func(_ int, _ string) bool { return true }
+	if c.filter == nil {
+		c.filter = func(_ int, _ string) bool { return true }
 	}
-	if p.cb == nil {
-		p.cb = func(k, v string) (string, string) {
-			return strings.TrimFunc(k, p.sepFunc), strings.TrimFunc(v, p.sepFunc)
+	if c.cb == nil {
+		sep := c.sepFunc
+		c.cb = func(k, v string) (string, string) {
+			return strings.TrimFunc(k, sep), strings.TrimFunc(v, sep)
 		}
 	}
-	if p.conf == nil {
-		p.conf = &mapstructure.DecoderConfig{TagName: "json"}
-	}
-	if p.conf.TagName == "" {
-		p.conf.TagName = "json"
-	}
+	return &c
 }
 
 // Table returns a new TableParser to parse a table
@@ -109,10 +161,9 @@ func Table() *TableParser {
 }
 
 func (p *TableParser) Unmarshal(b []byte, obj any) error {
-	p.lazyInit()
+	p = p.resolved()
 
-	p.conf.Result = obj
-	dec, err := mapstructure.NewDecoder(p.conf)
+	dec, err := newDecoder(p.conf, "json", obj)
 	if err != nil {
 		return errors.Wrap(err, "failed to create decoder")
 	}
@@ -132,6 +183,18 @@ func (p *TableParser) Unmarshal(b []byte, obj any) error {
 		return nil
 	}
 
+	if len(p.columns) != 0 {
+		ret, err := p.parseColumns(lines)
+		if err != nil {
+			return err
+		}
+		err = dec.Decode(ret)
+		if err != nil {
+			return errors.Wrap(err, "failed to decode")
+		}
+		return nil
+	}
+
 	// parse the header
 	headerTxt := p.headerTxt
 	if len(headerTxt) == 0 {
@@ -140,19 +203,23 @@ func (p *TableParser) Unmarshal(b []byte, obj any) error {
 			return nil
 		}
 	}
-	header := TableHeader(headerTxt)
-	headerIndex, err := header.Index(p.sepFunc, lines)
+	header := newDisplayLine(headerTxt)
+	dl := make([]displayLine, 0, len(lines))
+	for _, line := range lines {
+		dl = append(dl, newDisplayLine(line))
+	}
+	headerIndex, err := tableIndex(p.sepFunc, header, dl)
 	if err != nil {
 		return errors.Wrap(err, "failed to parse header")
 	}
 
 	// parse the table
-	ret := make([]map[string]any, 0, len(lines))
-	for _, line := range lines {
+	ret := make([]map[string]any, 0, len(dl))
+	for _, line := range dl {
 		item := make(map[string]any, len(headerIndex))
 		for _, h := range headerIndex {
-			k := headerTxt[h[0]:min(h[1], len(headerTxt))]
-			v := line[h[0]:min(h[1], len(line))]
+			k := header.slice(h[0], h[1])
+			v := line.slice(h[0], h[1])
 			k, v = p.cb(k, v)
 			item[k] = v
 		}
@@ -165,3 +232,92 @@ func (p *TableParser) Unmarshal(b []byte, obj any) error {
 	}
 	return nil
 }
+
+// parseColumns parses the lines using the declared columns.
+func (p *TableParser) parseColumns(lines []string) ([]map[string]any, error) {
+	var header displayLine
+	switch {
+	case p.noHeader:
+	case len(p.headerTxt) != 0:
+		header = newDisplayLine(p.headerTxt)
+	default:
+		header, lines = newDisplayLine(lines[0]), lines[1:]
+	}
+
+	// resolve the boundaries of the columns
+	columns := slices.Clone(p.columns)
+	for i, c := range columns {
+		if !c.anchor {
+			continue
+		}
+		if p.noHeader {
+			return nil, errors.Newf("column %q is anchored but there is no header", c.name)
+		}
+		idx := indexWord(header.text, c.name, p.sepFunc)
+		if idx < 0 {
+			return nil, errors.Newf("column %q is not found in the header", c.name)
+		}
+		columns[i].start = header.cols[utf8.RuneCountInString(header.text[:idx])]
+	}
+	slices.SortStableFunc(columns, func(a, b TableColumn) int { return a.start - b.start })
+	for i, c := range columns {
+		if !c.anchor {
+			continue
+		}
+		columns[i].end = -1
+		if i+1 < len(columns) {
+			columns[i].end = columns[i+1].start
+		}
+	}
+
+	isSep := func(l displayLine, col int) bool {
+		r, ok := l.at(col)
+		return !ok || p.sepFunc(r)
+	}
+
+	ret := make([]map[string]any, 0, len(lines))
+	for _, line := range lines {
+		l := newDisplayLine(line)
+		item := make(map[string]any, len(columns))
+		prevEnd := 0
+		for _, c := range columns {
+			start, end := max(c.start, prevEnd), c.end
+			if end < 0 || end > l.width {
+				end = l.width
+			}
+			// extend an anchored column if a value overflows its end,
+			// the offsets of the other columns are exact
+			if c.anchor && end > start && !isSep(l, end-1) {
+				for !isSep(l, end) {
+					end++
+				}
+			}
+			end = max(start, end)
+			prevEnd = end
+			k, v := p.cb(c.name, l.slice(start, end))
+			item[k] = v
+		}
+		ret = append(ret, item)
+	}
+	return ret, nil
+}
+
+// indexWord returns the byte index of the first occurrence of word in s
+// which is surrounded by separators, e.g. "NAME" in "NAMESPACE  NAME".
+// If there is no such occurrence, it returns the index of the first occurrence, or -1.
+func indexWord(s, word string, isSep func(rune) bool) int {
+	first := strings.Index(s, word)
+	for i := first; i >= 0; {
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		after, _ := utf8.DecodeRuneInString(s[i+len(word):])
+		if (i == 0 || isSep(before)) && (i+len(word) == len(s) || isSep(after)) {
+			return i
+		}
+		next := strings.Index(s[i+1:], word)
+		if next < 0 {
+			break
+		}
+		i += 1 + next
+	}
+	return first
+}
@@ -3,6 +3,7 @@ package parser_test
 import (
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/onsi/gomega/gmeasure"
 
@@ -196,6 +197,222 @@ foofoofoofoofoofoofoofoofoofoof-28516530-sxr74                    0/1     Comple
 	})
 })
 
+var _ = Describe("TableParser - Unicode", func() {
+	p := parser.Table()
+	var got []map[string]any
+
+	BeforeEach(func() {
+		got = nil
+	})
+
+	When("input has mixed scripts", func() {
+		It("return expected object", func() {
+			buf := []byte(`
+Permissions Size User Name        Date Modified
+drwxr-xr-x     - foo  résumé      21 Mar 09:58
+.rw-r--r--  1.0k foo  文档.txt    21 Mar 10:11
+.rw-r--r--   12k foo  Ωmega файл  21 Mar 10:11
+.rw-r--r--   342 bär  main.go     21 Mar 09:59
+`[1:])
+
+			err := p.Unmarshal(buf, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{
+				{
+					"Permissions":   "drwxr-xr-x",
+					"Size":          "-",
+					"User":          "foo",
+					"Name":          "résumé",
+					"Date Modified": "21 Mar 09:58",
+				},
+				{
+					"Permissions":   ".rw-r--r--",
+					"Size":          "1.0k",
+					"User":          "foo",
+					"Name":          "文档.txt",
+					"Date Modified": "21 Mar 10:11",
+				},
+				{
+					"Permissions":   ".rw-r--r--",
+					"Size":          "12k",
+					"User":          "foo",
+					"Name":          "Ωmega файл",
+					"Date Modified": "21 Mar 10:11",
+				},
+				{
+					"Permissions":   ".rw-r--r--",
+					"Size":          "342",
+					"User":          "bär",
+					"Name":          "main.go",
+					"Date Modified": "21 Mar 09:59",
+				},
+			}))
+		})
+	})
+
+	When("header has wide characters", func() {
+		It("return expected object", func() {
+			buf := []byte(`
+名称      状态
+服务一    运行中
+svc-2     stopped
+`[1:])
+
+			err := p.Unmarshal(buf, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{
+				{"名称": "服务一", "状态": "运行中"},
+				{"名称": "svc-2", "状态": "stopped"},
+			}))
+		})
+	})
+
+	When("input has tabs", func() {
+		It("return expected object", func() {
+			buf := []byte("NAME\tVALUE\nfoo\tbar\nlonger\tbaz\n")
+
+			err := p.Unmarshal(buf, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{
+				{"NAME": "foo", "VALUE": "bar"},
+				{"NAME": "longer", "VALUE": "baz"},
+			}))
+		})
+	})
+})
+
+var _ = Describe("TableHeader", func() {
+	It("return display column boundaries", func() {
+		idx, err := parser.TableHeader("名称  ID").
+			Index(unicode.IsSpace, []string{"服务  1", "ab    22"})
+		Expect(err).To(BeNil())
+		Expect(idx).To(Equal([][2]int{{0, 4}, {6, 8}}))
+	})
+})
+
+var _ = Describe("TableParser - Columns", func() {
+	var got []map[string]any
+
+	BeforeEach(func() {
+		got = nil
+	})
+
+	When("a value overflows its column", func() {
+		It("return expected object", func() {
+			p := parser.Table().WithColumns(
+				parser.ColumnAnchor("NAME"),
+				parser.ColumnAnchor("READY"),
+				parser.ColumnAnchor("STATUS"),
+			)
+			err := p.Unmarshal([]byte(`
+NAME         READY   STATUS
+foo-1        1/1     Running
+foo-long-name-2 0/1  CrashLoopBackOff
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{
+				{"NAME": "foo-1", "READY": "1/1", "STATUS": "Running"},
+				{"NAME": "foo-long-name-2", "READY": "0/1", "STATUS": "CrashLoopBackOff"},
+			}))
+		})
+	})
+
+	When("the last column contains separators", func() {
+		It("return expected object", func() {
+			p := parser.Table().WithColumns(
+				parser.ColumnAnchor("CONTAINER ID"),
+				parser.ColumnAnchor("COMMAND"),
+				parser.ColumnAnchor("NAMES"),
+			)
+			err := p.Unmarshal([]byte(`
+CONTAINER ID   COMMAND                  NAMES
+4c01db0b339c   "docker-entrypoint.s…"   my db
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{
+				{
+					"CONTAINER ID": "4c01db0b339c",
+					"COMMAND":      `"docker-entrypoint.s…"`,
+					"NAMES":        "my db",
+				},
+			}))
+		})
+	})
+
+	When("an anchor is a part of another header", func() {
+		It("return expected object", func() {
+			p := parser.Table().WithColumns(
+				parser.ColumnAnchor("NAMESPACE"),
+				parser.ColumnAnchor("NAME"),
+			)
+			err := p.Unmarshal([]byte(`
+NAMESPACE   NAME
+default     foo
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{{"NAMESPACE": "default", "NAME": "foo"}}))
+		})
+	})
+
+	When("input has no header", func() {
+		It("return expected object", func() {
+			p := parser.Table().WithNoHeader().WithColumns(
+				parser.ColumnOffset("perm", 0, 10),
+				parser.ColumnOffset("name", 11, -1),
+			)
+			err := p.Unmarshal([]byte(`
+drwxr-xr-x my dir
+.rw-r--r-- go.mod
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{
+				{"perm": "drwxr-xr-x", "name": "my dir"},
+				{"perm": ".rw-r--r--", "name": "go.mod"},
+			}))
+		})
+	})
+
+	When("columns are adjacent fixed-width fields", func() {
+		It("honors the offsets exactly", func() {
+			p := parser.Table().WithNoHeader().WithColumns(
+				parser.ColumnOffset("a", 0, 4),
+				parser.ColumnOffset("b", 4, 8),
+			)
+			err := p.Unmarshal([]byte("AAAABBBB\n"), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{{"a": "AAAA", "b": "BBBB"}}))
+		})
+	})
+
+	When("input has wide characters", func() {
+		It("return expected object", func() {
+			p := parser.Table().WithColumns(
+				parser.ColumnAnchor("名前"),
+				parser.ColumnAnchor("SIZE"),
+				parser.ColumnAnchor("USER"),
+			)
+			err := p.Unmarshal([]byte(`
+名前        SIZE USER
+文件.txt    12k  foo
+café.md     1.0k bar
+`[1:]), &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal([]map[string]any{
+				{"名前": "文件.txt", "SIZE": "12k", "USER": "foo"},
+				{"名前": "café.md", "SIZE": "1.0k", "USER": "bar"},
+			}))
+		})
+	})
+
+	When("an anchor is not found", func() {
+		It("return error", func() {
+			p := parser.Table().WithColumns(parser.ColumnAnchor("FOO"))
+			err := p.Unmarshal([]byte("BAR\nbaz\n"), &got)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
+
 func generateTableBenchmarkInput(i int) []byte {
 	input := "Permissions Size User Date Modified Name\n"
 	input += strings.Repeat(`
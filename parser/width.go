@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"sort"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// tabWidth is the distance between tab stops when expanding tabs.
+const tabWidth = 8
+
+// widthCond measures the display width of runes.
+// Ambiguous characters are narrow, regardless of the locale.
+var widthCond = &runewidth.Condition{EastAsianWidth: false}
+
+// displayLine is a line indexed by display columns instead of bytes.
+// East-Asian wide characters occupy two columns, combining characters occupy none,
+// and tabs are expanded to the next tab stop.
+type displayLine struct {
+	text string
+	// cols is the display column where each rune starts
+	cols []int
+	// offs is the byte offset of each rune, followed by len(text)
+	offs []int
+	// runes of the line
+	runes []rune
+	// width is the display width of the line
+	width int
+}
+
+func newDisplayLine(s string) displayLine {
+	l := displayLine{
+		text:  s,
+		cols:  make([]int, 0, len(s)),
+		offs:  make([]int, 0, len(s)+1),
+		runes: make([]rune, 0, len(s)),
+	}
+	col := 0
+	for i, r := range s {
+		l.cols = append(l.cols, col)
+		l.offs = append(l.offs, i)
+		l.runes = append(l.runes, r)
+		if r == '\t' {
+			col += tabWidth - col%tabWidth
+		} else {
+			col += widthCond.RuneWidth(r)
+		}
+	}
+	l.offs = append(l.offs, len(s))
+	l.width = col
+	return l
+}
+
+// index returns the index of the first rune starting at or after the display column.
+func (l displayLine) index(col int) int {
+	return sort.SearchInts(l.cols, col)
+}
+
+// slice returns the runes starting in the display columns [start, end).
+// If end is negative, it returns the runes up to the end of the line.
+// A wide rune belongs to the column where it starts, so it is never split.
+func (l displayLine) slice(start, end int) string {
+	i := l.index(start)
+	j := len(l.runes)
+	if end >= 0 {
+		j = max(i, l.index(end))
+	}
+	return l.text[l.offs[i]:l.offs[j]]
+}
+
+// at returns the rune occupying the display column.
+// It returns false if the column is out of the line.
+func (l displayLine) at(col int) (rune, bool) {
+	if col < 0 || col >= l.width {
+		return 0, false
+	}
+	i := sort.Search(len(l.cols), func(i int) bool { return l.cols[i] > col }) - 1
+	return l.runes[i], true
+}
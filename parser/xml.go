@@ -0,0 +1,246 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// XMLParser parses and encodes xml.
+//
+// If the target is a struct, it uses encoding/xml, so the fields are tagged with `xml:"..."`.
+// Otherwise, it decodes the document into a generic map[string]any tree
+// and decodes the tree using mapstructure:
+//   - the root element is the only key of the tree.
+//   - attributes are keyed by the attribute prefix ("-" by default) followed by their names.
+//   - elements without attributes and children are decoded as their text.
+//   - text of other elements is keyed by the text key ("#text" by default).
+//   - repeated elements are decoded as []any.
+type XMLParser struct {
+	conf       *mapstructure.DecoderConfig
+	attrPrefix string
+	textKey    string
+	indent     string
+}
+
+func (p *XMLParser) WithDecoderConfig(conf *mapstructure.DecoderConfig) *XMLParser {
+	return with(p, func(c *XMLParser) { c.conf = copyConfig(conf) })
+}
+
+// WithAttrPrefix sets the prefix of attribute keys in the generic tree.
+func (p *XMLParser) WithAttrPrefix(prefix string) *XMLParser {
+	return with(p, func(c *XMLParser) { c.attrPrefix = prefix })
+}
+
+// WithTextKey sets the key of element text in the generic tree.
+func (p *XMLParser) WithTextKey(key string) *XMLParser {
+	return with(p, func(c *XMLParser) { c.textKey = key })
+}
+
+// WithIndent sets the indent used by Marshal.
+func (p *XMLParser) WithIndent(indent string) *XMLParser {
+	return with(p, func(c *XMLParser) { c.indent = indent })
+}
+
+// resolved returns a copy of p with the defaults filled in.
+func (p *XMLParser) resolved() *XMLParser {
+	c := *p
+	if c.attrPrefix == "" {
+		c.attrPrefix = "-"
+	}
+	if c.textKey == "" {
+		c.textKey = "#text"
+	}
+	return &c
+}
+
+// XML returns a new XMLParser
+func XML() *XMLParser {
+	return &XMLParser{}
+}
+
+func (p *XMLParser) Unmarshal(b []byte, v any) error {
+	p = p.resolved()
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && rv.Elem().Kind() == reflect.Struct {
+		err := xml.Unmarshal(b, v)
+		if err != nil {
+			return errors.Wrapf(err, "unmarshal failed")
+		}
+		return nil
+	}
+
+	dec, err := newDecoder(p.conf, "json", v)
+	if err != nil {
+		return errors.Wrapf(err, "create decoder failed")
+	}
+
+	raw := make(map[string]any)
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errors.Wrapf(err, "read token failed")
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if len(raw) != 0 {
+				return errors.New("multiple root elements")
+			}
+			raw[start.Name.Local], err = p.decodeElement(d, start)
+			if err != nil {
+				return errors.Wrapf(err, "read element failed")
+			}
+		}
+	}
+
+	err = dec.Decode(raw)
+	if err != nil {
+		return errors.Wrapf(err, "decode failed")
+	}
+	return nil
+}
+
+// decodeElement decodes the element started by start into the generic tree.
+func (p *XMLParser) decodeElement(d *xml.Decoder, start xml.StartElement) (any, error) {
+	m := make(map[string]any)
+	for _, attr := range start.Attr {
+		m[p.attrPrefix+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := p.decodeElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+			switch exist := m[t.Name.Local].(type) {
+			case nil:
+				m[t.Name.Local] = child
+			case []any:
+				m[t.Name.Local] = append(exist, child)
+			default:
+				m[t.Name.Local] = []any{exist, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(m) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				m[p.textKey] = s
+			}
+			return m, nil
+		}
+	}
+}
+
+// Marshal encodes v as xml.
+// If v is a generic map[string]any tree described in XMLParser, it is encoded in the reverse way,
+// the keys are encoded in lexical order.
+// Otherwise, it uses encoding/xml.
+func (p *XMLParser) Marshal(v any) ([]byte, error) {
+	p = p.resolved()
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		b, err := xml.MarshalIndent(v, "", p.indent)
+		if err != nil {
+			return nil, errors.Wrapf(err, "marshal failed")
+		}
+		return b, nil
+	}
+	if len(m) != 1 {
+		return nil, errors.Newf("expected exactly one root element, got %d", len(m))
+	}
+
+	var buf bytes.Buffer
+	e := xml.NewEncoder(&buf)
+	e.Indent("", p.indent)
+	for name, value := range m {
+		if err := p.encodeElement(e, name, value); err != nil {
+			return nil, errors.Wrapf(err, "encode failed")
+		}
+	}
+	if err := e.Close(); err != nil {
+		return nil, errors.Wrapf(err, "encode failed")
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeElement encodes the generic tree v as the element name.
+func (p *XMLParser) encodeElement(e *xml.Encoder, name string, v any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		if v != nil {
+			if err := e.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var children []string
+	for _, k := range keys {
+		switch {
+		case k == p.textKey:
+		case strings.HasPrefix(k, p.attrPrefix):
+			start.Attr = append(start.Attr, xml.Attr{
+				Name:  xml.Name{Local: strings.TrimPrefix(k, p.attrPrefix)},
+				Value: fmt.Sprint(m[k]),
+			})
+		default:
+			children = append(children, k)
+		}
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if text, ok := m[p.textKey]; ok {
+		if err := e.EncodeToken(xml.CharData(fmt.Sprint(text))); err != nil {
+			return err
+		}
+	}
+	for _, k := range children {
+		items, ok := m[k].([]any)
+		if !ok {
+			items = []any{m[k]}
+		}
+		for _, item := range items {
+			if err := p.encodeElement(e, k, item); err != nil {
+				return err
+			}
+		}
+	}
+	return e.EncodeToken(start.End())
+}
@@ -0,0 +1,161 @@
+package parser_test
+
+import (
+	"encoding/xml"
+
+	"github.com/tlipoca9/yevna/parser"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("XMLParser", func() {
+	report := []byte(`
+<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="FooTest" tests="2" failures="1">
+  <properties>
+    <property name="java.version" value="17"/>
+  </properties>
+  <testcase name="testA" classname="FooTest" time="0.01"/>
+  <testcase name="testB" classname="FooTest" time="0.02">
+    <failure message="expected 1">stack trace</failure>
+  </testcase>
+  <system-out>hello</system-out>
+</testsuite>
+`[1:])
+
+	Context("Generic", func() {
+		p := parser.XML()
+		var got map[string]any
+
+		BeforeEach(func() {
+			got = nil
+		})
+
+		When("input is simple", func() {
+			It("return expected object", func() {
+				err := p.Unmarshal([]byte(`<root><FOO>BAR</FOO></root>`), &got)
+				Expect(err).To(BeNil())
+				Expect(got).To(Equal(map[string]any{"root": map[string]any{"FOO": "BAR"}}))
+			})
+		})
+
+		When("input has attributes and repeated elements", func() {
+			It("return expected object", func() {
+				err := p.Unmarshal(report, &got)
+				Expect(err).To(BeNil())
+				Expect(got).To(Equal(map[string]any{
+					"testsuite": map[string]any{
+						"-name":     "FooTest",
+						"-tests":    "2",
+						"-failures": "1",
+						"properties": map[string]any{
+							"property": map[string]any{"-name": "java.version", "-value": "17"},
+						},
+						"testcase": []any{
+							map[string]any{
+								"-name":      "testA",
+								"-classname": "FooTest",
+								"-time":      "0.01",
+							},
+							map[string]any{
+								"-name":      "testB",
+								"-classname": "FooTest",
+								"-time":      "0.02",
+								"failure": map[string]any{
+									"-message": "expected 1",
+									"#text":    "stack trace",
+								},
+							},
+						},
+						"system-out": "hello",
+					},
+				}))
+			})
+		})
+
+		When("input has custom attribute prefix and text key", func() {
+			It("return expected object", func() {
+				p := parser.XML().WithAttrPrefix("@").WithTextKey("_")
+				err := p.Unmarshal([]byte(`<a id="1">text</a>`), &got)
+				Expect(err).To(BeNil())
+				Expect(got).To(Equal(map[string]any{"a": map[string]any{"@id": "1", "_": "text"}}))
+			})
+		})
+
+		When("input has multiple root elements", func() {
+			It("return error", func() {
+				err := p.Unmarshal([]byte(`<a/><b/>`), &got)
+				Expect(err).NotTo(BeNil())
+			})
+		})
+	})
+
+	Context("Struct", func() {
+		type testcase struct {
+			Name    string `xml:"name,attr"`
+			Failure *struct {
+				Message string `xml:"message,attr"`
+			} `xml:"failure"`
+		}
+		type testsuite struct {
+			XMLName   xml.Name   `xml:"testsuite"`
+			Name      string     `xml:"name,attr"`
+			Tests     int        `xml:"tests,attr"`
+			TestCases []testcase `xml:"testcase"`
+		}
+
+		It("return expected object", func() {
+			var got testsuite
+			err := parser.XML().Unmarshal(report, &got)
+			Expect(err).To(BeNil())
+			Expect(got.Name).To(Equal("FooTest"))
+			Expect(got.Tests).To(Equal(2))
+			Expect(got.TestCases).To(HaveLen(2))
+			Expect(got.TestCases[0].Failure).To(BeNil())
+			Expect(got.TestCases[1].Failure.Message).To(Equal("expected 1"))
+		})
+	})
+
+	Context("Marshal", func() {
+		It("encode generic tree", func() {
+			b, err := parser.XML().Marshal(map[string]any{
+				"project": map[string]any{
+					"-xmlns":  "http://maven.apache.org/POM/4.0.0",
+					"version": "1.0.0",
+					"module":  []any{"api", "core"},
+					"name":    map[string]any{"-lang": "en", "#text": "demo"},
+				},
+			})
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal(
+				`<project xmlns="http://maven.apache.org/POM/4.0.0">` +
+					`<module>api</module><module>core</module>` +
+					`<name lang="en">demo</name><version>1.0.0</version></project>`,
+			))
+		})
+
+		It("round trip generic tree", func() {
+			p := parser.XML().WithIndent("  ")
+			var tree map[string]any
+			err := p.Unmarshal(report, &tree)
+			Expect(err).To(BeNil())
+			b, err := p.Marshal(tree)
+			Expect(err).To(BeNil())
+			var got map[string]any
+			err = p.Unmarshal(b, &got)
+			Expect(err).To(BeNil())
+			Expect(got).To(Equal(tree))
+		})
+
+		It("encode struct", func() {
+			type item struct {
+				XMLName xml.Name `xml:"item"`
+				ID      string   `xml:"id,attr"`
+			}
+			b, err := parser.XML().Marshal(item{ID: "1"})
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal(`<item id="1"></item>`))
+		})
+	})
+})